@@ -2,9 +2,8 @@ module cat2/liftoff
 
 go 1.23.4
 
-require github.com/BurntSushi/toml v1.4.0
-
 require (
-	golang.org/x/sys v0.29.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/BurntSushi/toml v1.4.0
+	golang.org/x/sys v0.29.0
+	gopkg.in/yaml.v3 v3.0.1
 )
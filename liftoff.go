@@ -3,13 +3,20 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"cat2/liftoff/module"
 	"cat2/liftoff/util"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "registry" {
+		os.Exit(registryCommand(os.Args[2:]))
+	}
+
 	configPath := flag.String("config", "", "Path to configuration file")
+	requireChecksums := flag.Bool("require-checksums", false, "Fail downloads that have no sha256 or sha512")
 	flag.Parse()
 
 	logger := util.NewLogger(true)
@@ -32,6 +39,9 @@ func main() {
 		logger.Error("Failed to load configuration")
 		os.Exit(1)
 	}
+	if *requireChecksums {
+		config.Downloads.RequireChecksums = true
+	}
 
 	
 	if err := util.InstallChocolatey(logger); err != nil {
@@ -62,14 +72,23 @@ func main() {
 	}
 
 	
-	if err := module.NewWSLManager(logger).Configure(config.WSL); err != nil {
+	downloads := module.NewDownloadManager(logger)
+	if err := downloads.UseProxy(config.Network.Proxy); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	
+	wsl := module.NewWSLManager(logger)
+	wsl.UseDownloads(downloads, config.Downloads)
+	if err := wsl.Configure(config.WSL); err != nil {
 		logger.Error("Failed to configure WSL")
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
 
 	
-	if err := module.NewDownloadManager(logger).Download(config.Downloads); err != nil {
+	if err := downloads.Download(config.Downloads); err != nil {
 		logger.Error("Failed to download files")
 		logger.Error(err.Error())
 		os.Exit(1)
@@ -90,13 +109,56 @@ func main() {
 	}
 
 	
-	if len(config.Git.Repositories) > 0 {
-		if err := module.NewGitManager(logger).CloneMultiple(config.Git.Repositories); err != nil {
-			logger.Error("Failed to clone repositories")
-			logger.Error(err.Error())
-			os.Exit(1)
-		}
+	git := module.NewGitManager(logger)
+	git.UseEnvironment(config.Environment)
+	if err := git.UseProxy(config.Network.Proxy); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	if err := git.Configure(config.Git); err != nil {
+		logger.Error("Failed to configure git repositories")
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
 	logger.Success("System configuration completed successfully")
 }
+
+func registryCommand(args []string) int {
+	logger := util.NewLogger(true)
+
+	if len(args) == 0 || args[0] != "export" {
+		logger.Error("Unknown registry command")
+		logger.Info("Usage: liftoff registry export <root\\path> [--out file.reg]")
+		return 1
+	}
+
+	flags := flag.NewFlagSet("registry export", flag.ExitOnError)
+	out := flags.String("out", "", "Path of the .reg file to write")
+
+	var keyPath string
+	rest := args[1:]
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		keyPath, rest = rest[0], rest[1:]
+	}
+	flags.Parse(rest)
+	if keyPath == "" && flags.NArg() > 0 {
+		keyPath = flags.Arg(0)
+	}
+
+	if keyPath == "" {
+		logger.Error("No registry key specified")
+		logger.Info("Usage: liftoff registry export <root\\path> [--out file.reg]")
+		return 1
+	}
+
+	if *out == "" {
+		*out = filepath.Base(strings.ReplaceAll(keyPath, `\`, "/")) + ".reg"
+	}
+
+	if err := module.NewSystemConfigurator(logger).ExportRegistry(keyPath, *out); err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+	return 0
+}
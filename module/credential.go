@@ -0,0 +1,69 @@
+package module
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeDomainPassword  = 2
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32       = windows.NewLazySystemDLL("advapi32.dll")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+)
+
+type credential struct {
+	flags              uint32
+	credType           uint32
+	targetName         *uint16
+	comment            *uint16
+	lastWritten        windows.Filetime
+	credentialBlobSize uint32
+	credentialBlob     *byte
+	persist            uint32
+	attributeCount     uint32
+	attributes         uintptr
+	targetAlias        *uint16
+	userName           *uint16
+}
+
+func storeCredential(target, username, password string) error {
+	targetPtr, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return err
+	}
+	userPtr, err := windows.UTF16PtrFromString(username)
+	if err != nil {
+		return err
+	}
+	commentPtr, err := windows.UTF16PtrFromString("Managed by Liftoff")
+	if err != nil {
+		return err
+	}
+
+	secret := windows.StringToUTF16(password)
+	secret = secret[:len(secret)-1]
+
+	cred := credential{
+		credType:   credTypeDomainPassword,
+		targetName: targetPtr,
+		comment:    commentPtr,
+		persist:    credPersistLocalMachine,
+		userName:   userPtr,
+	}
+	if len(secret) > 0 {
+		cred.credentialBlobSize = uint32(len(secret) * 2)
+		cred.credentialBlob = (*byte)(unsafe.Pointer(&secret[0]))
+	}
+
+	ret, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("CredWrite failed: %w", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package module
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"cat2/liftoff/types"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	tcpipInterfacesPath  = `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\`
+	tcpip6InterfacesPath = `SYSTEM\CurrentControlSet\Services\Tcpip6\Parameters\Interfaces\`
+	dohInterfacesPath    = `SYSTEM\CurrentControlSet\Services\Dnscache\InterfaceSpecificParameters\`
+)
+
+
+var virtualAdapterMarkers = []string{"virtual", "hyper-v", "vpn", "tap-", "wintun", "wireguard", "loopback"}
+
+type networkAdapter struct {
+	Name        string
+	Description string
+	GUID        string
+	Index       uint32
+	IPv6Index   uint32
+	Type        uint32
+}
+
+
+func (a networkAdapter) physical() bool {
+	if a.Type != windows.IF_TYPE_ETHERNET_CSMACD && a.Type != windows.IF_TYPE_IEEE80211 {
+		return false
+	}
+	description := strings.ToLower(a.Description)
+	for _, marker := range virtualAdapterMarkers {
+		if strings.Contains(description, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+func listAdapters() ([]networkAdapter, error) {
+	size := uint32(15 * 1024)
+	var buf []byte
+	for {
+		buf = make([]byte, size)
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC,
+			windows.GAA_FLAG_SKIP_UNICAST|windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST|windows.GAA_FLAG_SKIP_DNS_SERVER,
+			0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_BUFFER_OVERFLOW {
+			return nil, err
+		}
+	}
+
+	var adapters []networkAdapter
+	for aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); aa != nil; aa = aa.Next {
+		if aa.IfType == windows.IF_TYPE_SOFTWARE_LOOPBACK {
+			continue
+		}
+		adapters = append(adapters, networkAdapter{
+			Name:        windows.UTF16PtrToString(aa.FriendlyName),
+			Description: windows.UTF16PtrToString(aa.Description),
+			GUID:        windows.BytePtrToString(aa.AdapterName),
+			Index:       aa.IfIndex,
+			IPv6Index:   aa.Ipv6IfIndex,
+			Type:        aa.IfType,
+		})
+	}
+	return adapters, nil
+}
+
+
+func matchDNSRule(rules []types.DNSRule, adapter networkAdapter) *types.DNSRule {
+	for i, rule := range rules {
+		if len(rule.Interfaces) == 0 {
+			if adapter.physical() {
+				return &rules[i]
+			}
+			continue
+		}
+		for _, pattern := range rule.Interfaces {
+			pattern = strings.ToLower(pattern)
+			for _, name := range []string{adapter.Name, adapter.Description} {
+				if ok, _ := filepath.Match(pattern, strings.ToLower(name)); ok {
+					return &rules[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+
+func splitDNSServers(servers []string) (v4, v6 []string, err error) {
+	for _, server := range servers {
+		addr, err := netip.ParseAddr(strings.TrimSpace(server))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid DNS server %q", server)
+		}
+		if addr.Is4() || addr.Is4In6() {
+			v4 = append(v4, addr.Unmap().String())
+		} else {
+			v6 = append(v6, addr.String())
+		}
+	}
+	return v4, v6, nil
+}
+
+func validateDNSRule(rule types.DNSRule) error {
+	if rule.DHCP && len(rule.Servers) > 0 {
+		return fmt.Errorf("DNS rule %v sets both dhcp and servers", rule.Interfaces)
+	}
+	if !rule.DHCP && len(rule.Servers) == 0 {
+		return fmt.Errorf("DNS rule %v has no servers", rule.Interfaces)
+	}
+	for server, template := range rule.DoHTemplates {
+		if _, err := netip.ParseAddr(server); err != nil {
+			return fmt.Errorf("invalid DoH server %q", server)
+		}
+		parsed, err := url.Parse(template)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("invalid DoH template for %s: %s", server, template)
+		}
+	}
+	return nil
+}
+
+func (n *NetworkManager) configureDNS(rules []types.DNSRule) error {
+	n.log.Info("Configuring DNS servers")
+
+	for _, rule := range rules {
+		if err := validateDNSRule(rule); err != nil {
+			n.log.Error("Invalid DNS configuration")
+			return err
+		}
+	}
+
+	adapters, err := listAdapters()
+	if err != nil {
+		n.log.Error("Failed to get network interfaces")
+		return fmt.Errorf("failed to get network interfaces: %w", err)
+	}
+
+	for server, template := range dohTemplates(rules) {
+		if err := n.setDoHTemplate(server, template); err != nil {
+			return err
+		}
+	}
+
+	matched := 0
+	for _, adapter := range adapters {
+		rule := matchDNSRule(rules, adapter)
+		if rule == nil {
+			continue
+		}
+		matched++
+
+		if rule.DHCP {
+			if err := n.setAdapterDNS(adapter, "ipv4", nil); err != nil {
+				return err
+			}
+			if err := n.setAdapterDNS(adapter, "ipv6", nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		v4, v6, _ := splitDNSServers(rule.Servers)
+		if len(v4) > 0 {
+			if err := n.setAdapterDNS(adapter, "ipv4", v4); err != nil {
+				return err
+			}
+		}
+		if len(v6) > 0 {
+			if err := n.setAdapterDNS(adapter, "ipv6", v6); err != nil {
+				return err
+			}
+		}
+		if err := n.enableDoH(adapter, rule); err != nil {
+			return err
+		}
+	}
+
+	if matched == 0 {
+		n.log.Warn("No network interface matched the DNS configuration")
+		return nil
+	}
+
+	n.log.Success("Successfully configured DNS servers")
+	return nil
+}
+
+
+func currentDNSServers(adapter networkAdapter, family string) ([]string, error) {
+	path := tcpipInterfacesPath
+	if family == "ipv6" {
+		path = tcpip6InterfacesPath
+	}
+
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, path+adapter.GUID, registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	value, _, err := key.GetStringValue("NameServer")
+	if err == registry.ErrNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }), nil
+}
+
+
+func (n *NetworkManager) setAdapterDNS(adapter networkAdapter, family string, servers []string) error {
+	index := adapter.Index
+	if family == "ipv6" {
+		index = adapter.IPv6Index
+	}
+	if index == 0 {
+		return nil
+	}
+
+	current, err := currentDNSServers(adapter, family)
+	if err != nil {
+		n.log.Error(fmt.Sprintf("Failed to read DNS servers for %s", adapter.Name))
+		return fmt.Errorf("failed to read DNS servers for %s: %w", adapter.Name, err)
+	}
+	if strings.Join(current, ",") == strings.Join(servers, ",") {
+		return nil
+	}
+
+	name := "name=" + strconv.FormatUint(uint64(index), 10)
+	if len(servers) == 0 {
+		n.log.Info(fmt.Sprintf("%s %s DNS: %s -> dhcp", adapter.Name, family, strings.Join(current, ",")))
+		if output, err := exec.Command("netsh", "interface", family, "set", "dnsservers", name, "source=dhcp").CombinedOutput(); err != nil {
+			n.log.Error(fmt.Sprintf("Failed to reset DNS for %s", adapter.Name))
+			return fmt.Errorf("failed to reset DNS for %s: %w: %s", adapter.Name, err, strings.TrimSpace(string(output)))
+		}
+		n.log.Success(fmt.Sprintf("Reset %s DNS for %s to DHCP", family, adapter.Name))
+		return nil
+	}
+
+	n.log.Info(fmt.Sprintf("%s %s DNS: %s -> %s", adapter.Name, family, dnsList(current), strings.Join(servers, ",")))
+
+	cmd := exec.Command("netsh", "interface", family, "set", "dnsservers", name,
+		"source=static", "address="+servers[0], "register=primary", "validate=no")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		n.log.Error(fmt.Sprintf("Failed to set primary DNS for %s", adapter.Name))
+		return fmt.Errorf("failed to set DNS for %s: %w: %s", adapter.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	for i, server := range servers[1:] {
+		addCmd := exec.Command("netsh", "interface", family, "add", "dnsservers", name,
+			"address="+server, fmt.Sprintf("index=%d", i+2), "validate=no")
+		if output, err := addCmd.CombinedOutput(); err != nil {
+			n.log.Error(fmt.Sprintf("Failed to add DNS server %s", server))
+			return fmt.Errorf("failed to add DNS server %s: %w: %s", server, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	n.log.Success(fmt.Sprintf("Set %s DNS for %s", family, adapter.Name))
+	return nil
+}
+
+func dnsList(servers []string) string {
+	if len(servers) == 0 {
+		return "dhcp"
+	}
+	return strings.Join(servers, ",")
+}
+
+func dohTemplates(rules []types.DNSRule) map[string]string {
+	templates := make(map[string]string)
+	for _, rule := range rules {
+		for server, template := range rule.DoHTemplates {
+			addr, _ := netip.ParseAddr(server)
+			templates[addr.Unmap().String()] = template
+		}
+	}
+	return templates
+}
+
+
+func (n *NetworkManager) setDoHTemplate(server, template string) error {
+	args := []string{"dns", "add", "encryption", "server=" + server, "dohtemplate=" + template,
+		"autoupgrade=yes", "udpfallback=no"}
+	if err := exec.Command("netsh", args...).Run(); err == nil {
+		n.log.Success(fmt.Sprintf("Registered DoH template for %s", server))
+		return nil
+	}
+
+	args[1] = "set"
+	if output, err := exec.Command("netsh", args...).CombinedOutput(); err != nil {
+		n.log.Error(fmt.Sprintf("Failed to register DoH template for %s", server))
+		return fmt.Errorf("failed to register DoH template for %s: %w: %s", server, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+
+func (n *NetworkManager) enableDoH(adapter networkAdapter, rule *types.DNSRule) error {
+	for server := range rule.DoHTemplates {
+		addr, _ := netip.ParseAddr(server)
+		addr = addr.Unmap()
+
+		family := "Doh"
+		if addr.Is6() {
+			family = "Doh6"
+		}
+		path := dohInterfacesPath + adapter.GUID + `\DohInterfaceSettings\` + family + `\` + addr.String()
+
+		key, _, err := registry.CreateKey(registry.LOCAL_MACHINE, path, registry.ALL_ACCESS)
+		if err != nil {
+			n.log.Error(fmt.Sprintf("Failed to enable DoH for %s on %s", addr, adapter.Name))
+			return fmt.Errorf("failed to enable DoH for %s on %s: %w", addr, adapter.Name, err)
+		}
+		if current, _, err := key.GetIntegerValue("DohFlags"); err == nil && current == 1 {
+			key.Close()
+			continue
+		}
+		err = key.SetQWordValue("DohFlags", 1)
+		key.Close()
+		if err != nil {
+			n.log.Error(fmt.Sprintf("Failed to enable DoH for %s on %s", addr, adapter.Name))
+			return fmt.Errorf("failed to enable DoH for %s on %s: %w", addr, adapter.Name, err)
+		}
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package module
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -12,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"cat2/liftoff/types"
@@ -23,11 +26,47 @@ const (
 	baseTimeout      = 30 * time.Second
 	maxRedirects     = 10
 	maxContentLength = 1024 * 1024 * 1024 
+
+	defaultConcurrency  = 4
+	defaultPerHostLimit = 2
 )
 
 type DownloadManager struct {
-	log    *util.Logger
-	client *http.Client
+	log       *util.Logger
+	client    *http.Client
+	transport *http.Transport
+	hosts     *hostLimiter
+}
+
+
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+func (l *hostLimiter) acquire(host string) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	l.mu.Lock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = make(chan struct{}, l.limit)
+		l.slots[host] = slot
+	}
+	l.mu.Unlock()
+
+	slot <- struct{}{}
+	return func() { <-slot }
 }
 
 func NewDownloadManager(log *util.Logger) *DownloadManager {
@@ -40,6 +79,7 @@ func NewDownloadManager(log *util.Logger) *DownloadManager {
 			KeepAlive: 30 * time.Second,
 		}).DialContext,
 		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   defaultConcurrency,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
@@ -53,25 +93,130 @@ func NewDownloadManager(log *util.Logger) *DownloadManager {
 			if len(via) >= maxRedirects {
 				return fmt.Errorf("stopped after %d redirects", maxRedirects)
 			}
+			
+			if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+				for name := range via[0].Header {
+					req.Header.Del(name)
+				}
+			}
 			return nil
 		},
 	}
 
 	return &DownloadManager{
-		log:    log,
-		client: client,
+		log:       log,
+		client:    client,
+		transport: transport,
+	}
+}
+
+func (d *DownloadManager) UseProxy(config types.ProxyConfig) error {
+	resolver, err := newProxyResolver(config)
+	if err != nil {
+		return fmt.Errorf("invalid proxy configuration: %w", err)
+	}
+
+	tlsConfig, err := resolver.tlsConfig()
+	if err != nil {
+		return err
 	}
+
+	d.transport.Proxy = resolver.transportProxy
+	d.transport.TLSClientConfig = tlsConfig
+
+	if config.Enable {
+		d.log.Info("Routing downloads through the configured proxy")
+	}
+	return nil
 }
 
 func (d *DownloadManager) Download(config types.DownloadConfig) error {
+	if len(config.Files) == 0 {
+		return nil
+	}
+
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	perHostLimit := config.PerHostLimit
+	if perHostLimit <= 0 {
+		perHostLimit = defaultPerHostLimit
+	}
+
+	
+	d.hosts = newHostLimiter(perHostLimit)
+	globalSlots := make(chan struct{}, concurrency)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []string
+		done     int
+		stopOnce sync.Once
+	)
+	stop := make(chan struct{})
+	total := len(config.Files)
+
+	d.log.Info(fmt.Sprintf("Downloading %d files (concurrency %d, per host %d)", total, concurrency, perHostLimit))
+
 	for _, file := range config.Files {
-		if err := d.downloadFile(file); err != nil {
-			return fmt.Errorf("failed to download %s: %w", file.URL, err)
+		wg.Add(1)
+		go func(file types.DownloadFile) {
+			defer wg.Done()
+
+			select {
+			case globalSlots <- struct{}{}:
+			case <-stop:
+				return
+			}
+			defer func() { <-globalSlots }()
+
+			
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			err := d.downloadFile(file, config)
+
+			mu.Lock()
+			done++
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("failed to download %s: %v", file.URL, err))
+				d.log.Error(fmt.Sprintf("[%d/%d] Failed %s", done, total, file.URL))
+			} else {
+				d.log.Info(fmt.Sprintf("[%d/%d] Finished %s", done, total, file.URL))
+			}
+			mu.Unlock()
+
+			if err != nil && config.FailFast {
+				stopOnce.Do(func() { close(stop) })
+			}
+		}(file)
+	}
+
+	wg.Wait()
+
+	if len(failures) > 0 {
+		if skipped := total - done; skipped > 0 {
+			failures = append(failures, fmt.Sprintf("%d downloads skipped after failure", skipped))
 		}
+		return fmt.Errorf("some files failed to download:\n%s", strings.Join(failures, "\n"))
 	}
+
 	return nil
 }
 
+func downloadHost(urlStr string) string {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Host)
+}
+
 func (d *DownloadManager) validateURL(urlStr string) error {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -98,7 +243,7 @@ type downloadResult struct {
 	err  error
 }
 
-func (d *DownloadManager) downloadWithRetry(urlStr string) ([]byte, error) {
+func (d *DownloadManager) downloadWithRetry(urlStr string, headers http.Header) ([]byte, error) {
 	var lastErr error
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
@@ -108,7 +253,7 @@ func (d *DownloadManager) downloadWithRetry(urlStr string) ([]byte, error) {
 
 		result := make(chan downloadResult, 1)
 		go func() {
-			data, err := d.downloadOnce(urlStr)
+			data, err := d.downloadOnce(urlStr, headers)
 			result <- downloadResult{data, err}
 		}()
 
@@ -128,11 +273,14 @@ func (d *DownloadManager) downloadWithRetry(urlStr string) ([]byte, error) {
 	return nil, fmt.Errorf("all download attempts failed: %v", lastErr)
 }
 
-func (d *DownloadManager) downloadOnce(urlStr string) ([]byte, error) {
+func (d *DownloadManager) downloadOnce(urlStr string, headers http.Header) ([]byte, error) {
 	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
 
 	resp, err := d.client.Do(req)
 	if err != nil {
@@ -169,9 +317,140 @@ func (d *DownloadManager) verifyChecksum(data []byte, expectedSHA256 string) err
 	return nil
 }
 
-func (d *DownloadManager) downloadFile(file types.DownloadFile) error {
-	if err := d.validateURL(file.URL); err != nil {
-		return fmt.Errorf("invalid URL %s: %w", file.URL, err)
+func (d *DownloadManager) verifySHA512(data []byte, expectedSHA512 string) error {
+	hasher := sha512.New()
+	hasher.Write(data)
+	actualChecksum := hex.EncodeToString(hasher.Sum(nil))
+
+	if !strings.EqualFold(actualChecksum, expectedSHA512) {
+		return fmt.Errorf("sha512 mismatch: expected %s, got %s", expectedSHA512, actualChecksum)
+	}
+
+	return nil
+}
+
+func (d *DownloadManager) verifyChecksums(data []byte, file types.DownloadFile) error {
+	if file.SHA256 == "" && file.SHA512 == "" {
+		d.log.Warn("No checksum provided for verification")
+		return nil
+	}
+
+	d.log.Info("Verifying file checksum")
+	if file.SHA256 != "" {
+		if err := d.verifyChecksum(data, file.SHA256); err != nil {
+			return err
+		}
+	}
+	if file.SHA512 != "" {
+		if err := d.verifySHA512(data, file.SHA512); err != nil {
+			return err
+		}
+	}
+	d.log.Success("Checksum verified successfully")
+	return nil
+}
+
+func requestHeaders(urlStr string, file types.DownloadFile, config types.DownloadConfig) (http.Header, error) {
+	host := downloadHost(urlStr)
+	headers := make(http.Header)
+
+	for name, auth := range config.HostAuth {
+		if strings.EqualFold(name, host) {
+			if err := applyAuth(headers, auth); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	
+	if host == downloadHost(file.URL) {
+		if err := applyAuth(headers, file.Auth); err != nil {
+			return nil, err
+		}
+	}
+
+	return headers, nil
+}
+
+func applyAuth(headers http.Header, auth types.AuthConfig) error {
+	for name, ref := range auth.Headers {
+		value, err := util.ResolveSecret(ref)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", name, err)
+		}
+		headers.Set(name, value)
+	}
+
+	if auth.BearerToken != "" {
+		token, err := util.ResolveSecret(auth.BearerToken)
+		if err != nil {
+			return fmt.Errorf("bearer token: %w", err)
+		}
+		headers.Set("Authorization", "Bearer "+token)
+	}
+
+	if auth.Username != "" {
+		username, err := util.ResolveSecret(auth.Username)
+		if err != nil {
+			return fmt.Errorf("username: %w", err)
+		}
+		password, err := util.ResolveSecret(auth.Password)
+		if err != nil {
+			return fmt.Errorf("password: %w", err)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		headers.Set("Authorization", "Basic "+credentials)
+	}
+
+	return nil
+}
+
+func downloadSources(file types.DownloadFile) []string {
+	if len(file.URLs) > 0 {
+		return file.URLs
+	}
+	return []string{file.URL}
+}
+
+func (d *DownloadManager) fetch(file types.DownloadFile, config types.DownloadConfig) ([]byte, error) {
+	var failures []string
+
+	for _, source := range downloadSources(file) {
+		if err := d.validateURL(source); err != nil {
+			failures = append(failures, fmt.Sprintf("invalid URL %s: %v", source, err))
+			continue
+		}
+
+		headers, err := requestHeaders(source, file, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve credentials for %s: %w", source, err)
+		}
+
+		d.log.Info(fmt.Sprintf("Fetching %s", source))
+		release := d.hosts.acquire(downloadHost(source))
+		data, err := d.downloadWithRetry(source, headers)
+		release()
+		if err != nil {
+			d.log.Warn(fmt.Sprintf("Source %s failed, trying next", source))
+			failures = append(failures, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+
+		if err := d.verifyChecksums(data, file); err != nil {
+			d.log.Warn(fmt.Sprintf("Checksum mismatch from %s, trying next source", source))
+			failures = append(failures, fmt.Sprintf("%s: checksum verification failed: %v", source, err))
+			continue
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("all sources failed:\n%s", strings.Join(failures, "\n"))
+}
+
+func (d *DownloadManager) downloadFile(file types.DownloadFile, config types.DownloadConfig) error {
+	if config.RequireChecksums && file.SHA256 == "" && file.SHA512 == "" {
+		return fmt.Errorf("no checksum provided and checksums are required")
 	}
 
 	expandedDest := os.ExpandEnv(file.Dest)
@@ -183,21 +462,11 @@ func (d *DownloadManager) downloadFile(file types.DownloadFile) error {
 
 	d.log.Info(fmt.Sprintf("Downloading %s to %s", file.URL, expandedDest))
 
-	data, err := d.downloadWithRetry(file.URL)
+	data, err := d.fetch(file, config)
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
 
-	if file.SHA256 != "" {
-		d.log.Info("Verifying file checksum")
-		if err := d.verifyChecksum(data, file.SHA256); err != nil {
-			return fmt.Errorf("checksum verification failed: %w", err)
-		}
-		d.log.Success("Checksum verified successfully")
-	} else {
-		d.log.Warn("No checksum provided for verification")
-	}
-
 	tmpFile, err := os.CreateTemp(destDir, "download-*")
 	if err != nil {
 		return fmt.Errorf("failed to create temporary file: %w", err)
@@ -211,6 +480,12 @@ func (d *DownloadManager) downloadFile(file types.DownloadFile) error {
 	}
 	tmpFile.Close()
 
+	if file.Signature != (types.SignatureConfig{}) {
+		if err := d.verifySignature(tmpPath, file, config); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
+		}
+	}
+
 	finalPath := expandedDest
 	if file.Rename != "" {
 		finalPath = filepath.Join(filepath.Dir(expandedDest), file.Rename)
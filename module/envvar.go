@@ -3,16 +3,51 @@ package module
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"unsafe"
 
 	"cat2/liftoff/types"
 	"cat2/liftoff/util"
 
+	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 )
 
+const (
+	userEnvironmentPath    = `Environment`
+	machineEnvironmentPath = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+
+	hwndBroadcast    = 0xffff
+	wmSettingChange  = 0x001A
+	smtoAbortIfHung  = 0x0002
+	broadcastTimeout = 5000
+
+	maxPathLength    = 32767
+	pathLegacyLength = 2047
+	pathWarnLength   = pathLegacyLength * 9 / 10
+
+	environmentStateName = "environment"
+)
+
+var (
+	user32                  = windows.NewLazySystemDLL("user32.dll")
+	procSendMessageTimeoutW = user32.NewProc("SendMessageTimeoutW")
+
+	envReference = regexp.MustCompile(`%[^%\s]+%`)
+)
+
 type EnvironmentManager struct {
-	log *util.Logger
+	log     *util.Logger
+	changed bool
+	state   environmentState
+}
+
+
+type environmentState struct {
+	Variables map[string]map[string]string `json:"variables"`
+	Path      map[string][]string          `json:"path"`
 }
 
 func NewEnvironmentManager(log *util.Logger) *EnvironmentManager {
@@ -22,27 +57,126 @@ func NewEnvironmentManager(log *util.Logger) *EnvironmentManager {
 }
 
 func (e *EnvironmentManager) Configure(config types.EnvironmentConfig) error {
-	
-	if len(config.PathAppend) > 0 {
-		if err := e.appendToPath(config.PathAppend); err != nil {
+	if err := e.loadState(); err != nil {
+		return err
+	}
+
+	pathScope := scopeName(config.PathScope)
+	if len(config.PathAppend) > 0 || len(config.PathPrepend) > 0 || len(config.PathRemove) > 0 ||
+		config.PathPruneMissing || len(e.state.Path[pathScope]) > 0 {
+		if err := e.updatePath(config); err != nil {
 			return err
 		}
 	}
 
 	
+	for _, scope := range []string{"user", "machine"} {
+		if scope != pathScope && len(e.state.Path[scope]) > 0 {
+			if err := e.updatePath(types.EnvironmentConfig{PathScope: scope}); err != nil {
+				return err
+			}
+		}
+	}
+
+	
 	if len(config.Variables) > 0 {
 		if err := e.setVariables(config.Variables); err != nil {
 			return err
 		}
 	}
 
+	if err := e.removeStaleVariables(config.Variables); err != nil {
+		return err
+	}
+
+	if len(config.Unset) > 0 {
+		if err := e.unsetVariables(config.Unset, config.Variables); err != nil {
+			return err
+		}
+	}
+
+	if err := util.SaveState(environmentStateName, e.state); err != nil {
+		e.log.Error("Failed to save environment state")
+		return err
+	}
+
+	if e.changed {
+		e.broadcastChange()
+	}
+
 	return nil
 }
 
-func (e *EnvironmentManager) appendToPath(paths []string) error {
-	e.log.Info("Configuring PATH variable")
+func (e *EnvironmentManager) loadState() error {
+	e.state = environmentState{}
+	if err := util.LoadState(environmentStateName, &e.state); err != nil {
+		e.log.Error("Failed to load environment state")
+		return err
+	}
+	if e.state.Variables == nil {
+		e.state.Variables = make(map[string]map[string]string)
+	}
+	if e.state.Path == nil {
+		e.state.Path = make(map[string][]string)
+	}
+	return nil
+}
 
-	key, err := registry.OpenKey(registry.CURRENT_USER, `Environment`, registry.ALL_ACCESS)
+func (e *EnvironmentManager) ownedVariables(scope string) map[string]string {
+	owned := e.state.Variables[scope]
+	if owned == nil {
+		owned = make(map[string]string)
+		e.state.Variables[scope] = owned
+	}
+	return owned
+}
+
+func openEnvironmentKey(scope string) (registry.Key, error) {
+	switch strings.ToLower(scope) {
+	case "", "user":
+		return registry.OpenKey(registry.CURRENT_USER, userEnvironmentPath, registry.ALL_ACCESS)
+	case "machine", "system":
+		return registry.OpenKey(registry.LOCAL_MACHINE, machineEnvironmentPath, registry.ALL_ACCESS)
+	default:
+		return 0, fmt.Errorf("invalid environment scope: %s", scope)
+	}
+}
+
+func scopeName(scope string) string {
+	if strings.EqualFold(scope, "machine") || strings.EqualFold(scope, "system") {
+		return "machine"
+	}
+	return "user"
+}
+
+
+func setEnvironmentValue(key registry.Key, name, value string, expand bool) error {
+	if expand || envReference.MatchString(value) {
+		return key.SetExpandStringValue(name, value)
+	}
+	return key.SetStringValue(name, value)
+}
+
+func needsExpandType(value string, valueType uint32) bool {
+	return valueType != registry.EXPAND_SZ && envReference.MatchString(value)
+}
+
+func normalizePathEntry(entry string) string {
+	expanded, err := registry.ExpandString(entry)
+	if err != nil {
+		expanded = entry
+	}
+	expanded = strings.ReplaceAll(strings.TrimSpace(expanded), "/", `\`)
+	for len(expanded) > 3 && strings.HasSuffix(expanded, `\`) {
+		expanded = strings.TrimSuffix(expanded, `\`)
+	}
+	return strings.ToLower(expanded)
+}
+
+func (e *EnvironmentManager) updatePath(config types.EnvironmentConfig) error {
+	e.log.Info(fmt.Sprintf("Configuring %s PATH variable", scopeName(config.PathScope)))
+
+	key, err := openEnvironmentKey(config.PathScope)
 	if err != nil {
 		e.log.Error("Failed to open Environment registry key")
 		return fmt.Errorf("failed to open Environment registry key: %w", err)
@@ -50,7 +184,7 @@ func (e *EnvironmentManager) appendToPath(paths []string) error {
 	defer key.Close()
 
 	
-	currentPath, _, err := key.GetStringValue("Path")
+	currentPath, valueType, err := key.GetStringValue("Path")
 	if err != nil && err != registry.ErrNotExist {
 		e.log.Error("Failed to read PATH variable")
 		return fmt.Errorf("failed to read PATH variable: %w", err)
@@ -62,53 +196,310 @@ func (e *EnvironmentManager) appendToPath(paths []string) error {
 		pathComponents = strings.Split(currentPath, ";")
 	}
 
+	remove := make(map[string]bool)
+	for _, p := range config.PathRemove {
+		remove[normalizePathEntry(os.ExpandEnv(p))] = true
+	}
+
+	
+	scope := scopeName(config.PathScope)
+	configured := make(map[string]bool)
+	for _, p := range append(append([]string{}, config.PathPrepend...), config.PathAppend...) {
+		configured[normalizePathEntry(os.ExpandEnv(p))] = true
+	}
+	owned := make(map[string]bool)
+	for _, p := range e.state.Path[scope] {
+		normalized := normalizePathEntry(p)
+		owned[normalized] = true
+		if !configured[normalized] {
+			remove[normalized] = true
+		}
+	}
+
 	
 	pathMap := make(map[string]bool)
+	var kept []string
 	for _, p := range pathComponents {
-		pathMap[strings.TrimSpace(p)] = true
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		normalized := normalizePathEntry(p)
+		switch {
+		case pathMap[normalized]:
+			e.log.Info(fmt.Sprintf("Removed duplicate %s from PATH", p))
+		case remove[normalized]:
+			e.log.Success(fmt.Sprintf("Removed %s from PATH", p))
+		case config.PathPruneMissing && !envReference.MatchString(normalized) && !directoryExists(normalized):
+			e.log.Success(fmt.Sprintf("Pruned missing directory %s from PATH", p))
+		default:
+			pathMap[normalized] = true
+			kept = append(kept, p)
+		}
+	}
+
+	var prepend, ownedEntries []string
+	previous := kept
+	for _, newPath := range config.PathPrepend {
+		expandedPath := os.ExpandEnv(newPath)
+		normalized := normalizePathEntry(expandedPath)
+		if pathMap[normalized] {
+			
+			kept = removePathEntry(kept, normalized)
+		}
+		if !pathMap[normalized] || owned[normalized] {
+			ownedEntries = append(ownedEntries, expandedPath)
+		}
+		
+		if i := len(prepend); i >= len(previous) || normalizePathEntry(previous[i]) != normalized {
+			e.log.Success(fmt.Sprintf("Prepended %s to PATH", expandedPath))
+		}
+		pathMap[normalized] = true
+		prepend = append(prepend, expandedPath)
 	}
+	pathComponents = append(prepend, kept...)
 
-	modified := false
-	for _, newPath := range paths {
+	for _, newPath := range config.PathAppend {
 		expandedPath := os.ExpandEnv(newPath)
-		if !pathMap[expandedPath] {
+		normalized := normalizePathEntry(expandedPath)
+		if !pathMap[normalized] {
 			pathComponents = append(pathComponents, expandedPath)
-			pathMap[expandedPath] = true
-			modified = true
+			pathMap[normalized] = true
+			ownedEntries = append(ownedEntries, expandedPath)
 			e.log.Success(fmt.Sprintf("Added %s to PATH", expandedPath))
+		} else if owned[normalized] {
+			ownedEntries = append(ownedEntries, expandedPath)
 		}
 	}
 
+	newPath := strings.Join(pathComponents, ";")
+	if newPath == currentPath && !needsExpandType(newPath, valueType) {
+		e.state.Path[scope] = ownedEntries
+		return nil
+	}
+
 	
-	if modified {
-		newPath := strings.Join(pathComponents, ";")
-		if err := key.SetStringValue("Path", newPath); err != nil {
-			e.log.Error("Failed to update PATH variable")
-			return fmt.Errorf("failed to update PATH variable: %w", err)
-		}
+	combined := len(newPath)
+	if other := otherScopePath(scope); other != "" {
+		combined += len(other) + 1
+	}
+	if combined > maxPathLength {
+		e.log.Error("PATH exceeds the maximum environment variable length")
+		return fmt.Errorf("combined machine and user PATH would be %d characters, the limit is %d", combined, maxPathLength)
+	}
+	if combined > pathWarnLength {
+		e.log.Warn(fmt.Sprintf("Combined machine and user PATH is %d characters, some tools truncate it after %d", combined, pathLegacyLength))
 	}
 
+	
+	if err := setEnvironmentValue(key, "Path", newPath, valueType == registry.EXPAND_SZ); err != nil {
+		e.log.Error("Failed to update PATH variable")
+		return fmt.Errorf("failed to update PATH variable: %w", err)
+	}
+	e.changed = true
+	e.state.Path[scope] = ownedEntries
+
 	return nil
 }
 
-func (e *EnvironmentManager) setVariables(variables map[string]string) error {
-	e.log.Info("Setting environment variables")
-
-	key, err := registry.OpenKey(registry.CURRENT_USER, `Environment`, registry.ALL_ACCESS)
+func otherScopePath(scope string) string {
+	root, path := registry.LOCAL_MACHINE, machineEnvironmentPath
+	if scope == "machine" {
+		root, path = registry.CURRENT_USER, userEnvironmentPath
+	}
+	key, err := registry.OpenKey(root, path, registry.QUERY_VALUE)
 	if err != nil {
-		e.log.Error("Failed to open Environment registry key")
-		return fmt.Errorf("failed to open Environment registry key: %w", err)
+		return ""
 	}
 	defer key.Close()
+	value, _, _ := key.GetStringValue("Path")
+	return value
+}
+
+func removePathEntry(entries []string, normalized string) []string {
+	var result []string
+	for _, entry := range entries {
+		if normalizePathEntry(entry) != normalized {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func directoryExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
 
-	for name, value := range variables {
-		expandedValue := os.ExpandEnv(value)
-		if err := key.SetStringValue(name, expandedValue); err != nil {
-			e.log.Error(fmt.Sprintf("Failed to set %s", name))
-			return fmt.Errorf("failed to set %s: %w", name, err)
+func (e *EnvironmentManager) setVariables(variables map[string]types.EnvVariable) error {
+	e.log.Info("Setting environment variables")
+
+	byScope := make(map[string][]string)
+	for name, variable := range variables {
+		scope := scopeName(variable.Scope)
+		if variable.Scope != "" && scope == "user" && !strings.EqualFold(variable.Scope, "user") {
+			return fmt.Errorf("invalid environment scope for %s: %s", name, variable.Scope)
 		}
-		e.log.Success(fmt.Sprintf("Set %s=%s", name, expandedValue))
+		byScope[scope] = append(byScope[scope], name)
+	}
+
+	for _, scope := range []string{"user", "machine"} {
+		names := byScope[scope]
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+
+		key, err := openEnvironmentKey(scope)
+		if err != nil {
+			e.log.Error(fmt.Sprintf("Failed to open %s Environment registry key", scope))
+			return fmt.Errorf("failed to open %s Environment registry key: %w", scope, err)
+		}
+
+		owned := e.ownedVariables(scope)
+		for _, name := range names {
+			value := os.ExpandEnv(variables[name].Value)
+
+			current, valueType, err := key.GetStringValue(name)
+			switch {
+			case err == registry.ErrNotExist:
+				owned[name] = value
+			case err != nil && err != registry.ErrUnexpectedType:
+				key.Close()
+				e.log.Error(fmt.Sprintf("Failed to read %s", name))
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			case current == value && !needsExpandType(value, valueType):
+				if _, ok := owned[name]; ok {
+					owned[name] = value
+				}
+				continue
+			default:
+				
+				if _, ok := owned[name]; ok {
+					owned[name] = value
+				} else {
+					e.log.Info(fmt.Sprintf("%s already exists, it will not be removed with the config entry", name))
+				}
+			}
+
+			if err := setEnvironmentValue(key, name, value, false); err != nil {
+				key.Close()
+				e.log.Error(fmt.Sprintf("Failed to set %s", name))
+				return fmt.Errorf("failed to set %s: %w", name, err)
+			}
+			e.changed = true
+			e.log.Success(fmt.Sprintf("Set %s=%s (%s)", name, value, scope))
+		}
+		key.Close()
 	}
 
 	return nil
 }
+
+
+func (e *EnvironmentManager) removeStaleVariables(variables map[string]types.EnvVariable) error {
+	configured := make(map[string]bool)
+	for name, variable := range variables {
+		configured[scopeName(variable.Scope)+"\x00"+strings.ToLower(name)] = true
+	}
+
+	for _, scope := range []string{"user", "machine"} {
+		owned := e.state.Variables[scope]
+		var stale []string
+		for name := range owned {
+			if !configured[scope+"\x00"+strings.ToLower(name)] {
+				stale = append(stale, name)
+			}
+		}
+		if len(stale) == 0 {
+			continue
+		}
+		sort.Strings(stale)
+
+		key, err := openEnvironmentKey(scope)
+		if err != nil {
+			e.log.Error(fmt.Sprintf("Failed to open %s Environment registry key", scope))
+			return fmt.Errorf("failed to open %s Environment registry key: %w", scope, err)
+		}
+
+		for _, name := range stale {
+			current, _, err := key.GetStringValue(name)
+			switch {
+			case err == registry.ErrNotExist:
+			case err != nil || current != owned[name]:
+				e.log.Warn(fmt.Sprintf("%s was changed outside Liftoff, leaving it in place", name))
+			default:
+				if err := key.DeleteValue(name); err != nil {
+					key.Close()
+					e.log.Error(fmt.Sprintf("Failed to remove %s", name))
+					return fmt.Errorf("failed to remove %s: %w", name, err)
+				}
+				e.changed = true
+				e.log.Success(fmt.Sprintf("Removed %s (%s)", name, scope))
+			}
+			delete(owned, name)
+		}
+		key.Close()
+	}
+
+	return nil
+}
+
+func (e *EnvironmentManager) unsetVariables(unset []types.EnvUnset, variables map[string]types.EnvVariable) error {
+	e.log.Info("Removing environment variables")
+
+	for _, entry := range unset {
+		scope := scopeName(entry.Scope)
+		if entry.Scope != "" && scope == "user" && !strings.EqualFold(entry.Scope, "user") {
+			return fmt.Errorf("invalid environment scope for %s: %s", entry.Name, entry.Scope)
+		}
+		if variable, ok := variables[entry.Name]; ok && scopeName(variable.Scope) == scope {
+			return fmt.Errorf("%s is both set and unset in the %s scope", entry.Name, scope)
+		}
+
+		key, err := openEnvironmentKey(scope)
+		if err != nil {
+			e.log.Error(fmt.Sprintf("Failed to open %s Environment registry key", scope))
+			return fmt.Errorf("failed to open %s Environment registry key: %w", scope, err)
+		}
+
+		err = key.DeleteValue(entry.Name)
+		key.Close()
+		delete(e.ownedVariables(scope), entry.Name)
+
+		switch {
+		case err == registry.ErrNotExist:
+		case err != nil:
+			e.log.Error(fmt.Sprintf("Failed to remove %s", entry.Name))
+			return fmt.Errorf("failed to remove %s: %w", entry.Name, err)
+		default:
+			e.changed = true
+			e.log.Success(fmt.Sprintf("Removed %s (%s)", entry.Name, scope))
+		}
+	}
+
+	return nil
+}
+
+
+func (e *EnvironmentManager) broadcastChange() {
+	param, err := windows.UTF16PtrFromString("Environment")
+	if err != nil {
+		return
+	}
+
+	var result uintptr
+	ret, _, _ := procSendMessageTimeoutW.Call(
+		hwndBroadcast,
+		wmSettingChange,
+		0,
+		uintptr(unsafe.Pointer(param)),
+		smtoAbortIfHung,
+		broadcastTimeout,
+		uintptr(unsafe.Pointer(&result)),
+	)
+	if ret == 0 {
+		e.log.Warn("Failed to notify running applications of environment changes")
+		return
+	}
+	e.log.Info("Notified running applications of environment changes")
+}
@@ -0,0 +1,210 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cat2/liftoff/types"
+	"cat2/liftoff/util"
+)
+
+const (
+	firewallRulePrefix = "Liftoff: "
+	firewallStateName  = "firewall"
+)
+
+var firewallPort = regexp.MustCompile(`^\d{1,5}(-\d{1,5})?$`)
+
+type firewallState struct {
+	Rules map[string]string `json:"rules"`
+}
+
+func (n *NetworkManager) configureFirewall(rules []types.FirewallRule) error {
+	var state firewallState
+	if err := util.LoadState(firewallStateName, &state); err != nil {
+		n.log.Error("Failed to load firewall state")
+		return err
+	}
+	if state.Rules == nil {
+		state.Rules = make(map[string]string)
+	}
+	if len(rules) == 0 && len(state.Rules) == 0 {
+		return nil
+	}
+
+	n.log.Info("Configuring firewall rules")
+
+	desired := make(map[string][]string)
+	for _, rule := range rules {
+		args, err := firewallRuleArgs(rule)
+		if err != nil {
+			n.log.Error("Invalid firewall rule")
+			return err
+		}
+		name := firewallRulePrefix + rule.Name
+		if _, ok := desired[name]; ok {
+			return fmt.Errorf("duplicate firewall rule %q", rule.Name)
+		}
+		desired[name] = args
+	}
+
+	var stale []string
+	for name := range state.Rules {
+		if _, ok := desired[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+
+	var failed []string
+	for _, name := range stale {
+		n.log.Info(fmt.Sprintf("- %s", name))
+		if err := deleteFirewallRule(name); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		delete(state.Rules, name)
+		n.log.Success(fmt.Sprintf("Removed firewall rule %s", name))
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		args := desired[name]
+		hash := firewallRuleHash(args)
+		exists := firewallRuleExists(name)
+
+		switch {
+		case exists && state.Rules[name] == hash:
+			continue
+		case exists:
+			n.log.Info(fmt.Sprintf("~ %s: %s", name, strings.Join(args, " ")))
+			if err := deleteFirewallRule(name); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+		default:
+			n.log.Info(fmt.Sprintf("+ %s: %s", name, strings.Join(args, " ")))
+		}
+
+		cmdArgs := append([]string{"advfirewall", "firewall", "add", "rule", "name=" + name}, args...)
+		if output, err := exec.Command("netsh", cmdArgs...).CombinedOutput(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v: %s", name, err, strings.TrimSpace(string(output))))
+			continue
+		}
+		state.Rules[name] = hash
+		n.log.Success(fmt.Sprintf("Applied firewall rule %s", name))
+	}
+
+	if err := util.SaveState(firewallStateName, state); err != nil {
+		n.log.Error("Failed to save firewall state")
+		return err
+	}
+
+	if len(failed) > 0 {
+		n.log.Error("Failed to apply some firewall rules")
+		return fmt.Errorf("some firewall rules failed:\n%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+func firewallRuleArgs(rule types.FirewallRule) ([]string, error) {
+	if strings.TrimSpace(rule.Name) == "" {
+		return nil, fmt.Errorf("firewall rule without a name")
+	}
+
+	direction := strings.ToLower(rule.Direction)
+	switch direction {
+	case "", "in", "inbound":
+		direction = "in"
+	case "out", "outbound":
+		direction = "out"
+	default:
+		return nil, fmt.Errorf("firewall rule %s: invalid direction %q", rule.Name, rule.Direction)
+	}
+
+	action := strings.ToLower(rule.Action)
+	switch action {
+	case "":
+		action = "allow"
+	case "allow", "block":
+	default:
+		return nil, fmt.Errorf("firewall rule %s: invalid action %q", rule.Name, rule.Action)
+	}
+
+	protocol := strings.ToLower(rule.Protocol)
+	switch protocol {
+	case "":
+		protocol = "tcp"
+	case "tcp", "udp", "any", "icmpv4", "icmpv6":
+	default:
+		return nil, fmt.Errorf("firewall rule %s: invalid protocol %q", rule.Name, rule.Protocol)
+	}
+
+	profile := strings.ToLower(strings.ReplaceAll(rule.Profile, " ", ""))
+	if profile == "" {
+		profile = "any"
+	}
+	for _, p := range strings.Split(profile, ",") {
+		switch p {
+		case "any", "domain", "private", "public":
+		default:
+			return nil, fmt.Errorf("firewall rule %s: invalid profile %q", rule.Name, p)
+		}
+	}
+
+	args := []string{"dir=" + direction, "action=" + action, "protocol=" + protocol, "profile=" + profile}
+
+	if len(rule.Ports) > 0 {
+		if protocol != "tcp" && protocol != "udp" {
+			return nil, fmt.Errorf("firewall rule %s: ports require tcp or udp", rule.Name)
+		}
+		for _, port := range rule.Ports {
+			if !firewallPort.MatchString(strings.TrimSpace(port)) {
+				return nil, fmt.Errorf("firewall rule %s: invalid port %q", rule.Name, port)
+			}
+		}
+
+		portArg := "localport="
+		if direction == "out" {
+			portArg = "remoteport="
+		}
+		args = append(args, portArg+strings.Join(rule.Ports, ","))
+	}
+
+	if rule.Program != "" {
+		args = append(args, "program="+os.ExpandEnv(rule.Program))
+	}
+
+	return append(args, "enable=yes"), nil
+}
+
+func firewallRuleHash(args []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func firewallRuleExists(name string) bool {
+	return exec.Command("netsh", "advfirewall", "firewall", "show", "rule", "name="+name).Run() == nil
+}
+
+func deleteFirewallRule(name string) error {
+	if !firewallRuleExists(name) {
+		return nil
+	}
+	output, err := exec.Command("netsh", "advfirewall", "firewall", "delete", "rule", "name="+name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
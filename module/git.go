@@ -2,29 +2,73 @@ package module
 
 import (
 	"fmt"
-	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"cat2/liftoff/types"
 	"cat2/liftoff/util"
 )
 
+const defaultGitConcurrency = 4
+
 type GitManager struct {
-	log *util.Logger
+	log          *util.Logger
+	proxy        *proxyResolver
+	trustedHosts []types.GitHost
+	knownHosts   string
+	sshCommand   string
+	concurrency  int
+	environment  types.EnvironmentConfig
 }
 
 func NewGitManager(log *util.Logger) *GitManager {
 	return &GitManager{
-		log: log,
+		log:   log,
+		proxy: &proxyResolver{},
+	}
+}
+
+func (g *GitManager) UseProxy(config types.ProxyConfig) error {
+	resolver, err := newProxyResolver(config)
+	if err != nil {
+		return fmt.Errorf("invalid proxy configuration: %w", err)
 	}
+	g.proxy = resolver
+	return nil
+}
+
+func (g *GitManager) Configure(config types.GitConfig) error {
+	g.trustedHosts = config.TrustedHosts
+	g.concurrency = config.Concurrency
+
+	if err := g.configureSSH(config.SSH); err != nil {
+		return fmt.Errorf("failed to configure SSH: %w", err)
+	}
+
+	if err := g.configureGlobal(config); err != nil {
+		return fmt.Errorf("failed to configure git settings: %w", err)
+	}
+
+	if len(config.Repositories) > 0 {
+		if err := g.CloneMultiple(config.Repositories); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (g *GitManager) CloneMultiple(repositories []types.Repository) error {
-	var errors []string
+	var (
+		failures []string
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		done     int
+	)
 
 	
 	failedDir := filepath.Join(os.ExpandEnv("${USERPROFILE}"), "Liftoff", "Failed")
@@ -32,14 +76,47 @@ func (g *GitManager) CloneMultiple(repositories []types.Repository) error {
 		return fmt.Errorf("failed to create failed operations directory: %w", err)
 	}
 
+	workers := g.concurrency
+	if workers <= 0 {
+		workers = defaultGitConcurrency
+	}
+	if workers > len(repositories) {
+		workers = len(repositories)
+	}
+
+	total := len(repositories)
+	jobs := make(chan types.Repository)
+
+	g.log.Info(fmt.Sprintf("Processing %d repositories with %d workers", total, workers))
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range jobs {
+				err := g.Clone(repo)
+
+				mu.Lock()
+				done++
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("failed to clone %s: %v", repo.URL, err))
+					g.log.Error(fmt.Sprintf("[%d/%d] Failed %s", done, total, repo.URL))
+				} else {
+					g.log.Info(fmt.Sprintf("[%d/%d] Finished %s", done, total, repo.URL))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
 	for _, repo := range repositories {
-		if err := g.Clone(repo); err != nil {
-			errors = append(errors, fmt.Sprintf("failed to clone %s: %v", repo.URL, err))
-		}
+		jobs <- repo
 	}
+	close(jobs)
+	wg.Wait()
 
-	if len(errors) > 0 {
-		return fmt.Errorf("some repositories failed to clone:\n%s", strings.Join(errors, "\n"))
+	if len(failures) > 0 {
+		return fmt.Errorf("some repositories failed to clone:\n%s", strings.Join(failures, "\n"))
 	}
 
 	return nil
@@ -48,8 +125,29 @@ func (g *GitManager) CloneMultiple(repositories []types.Repository) error {
 func (g *GitManager) Clone(config types.Repository) error {
 	expandedPath := os.ExpandEnv(config.Path)
 
+	sources := config.URLs
+	if len(sources) == 0 {
+		sources = []string{config.URL}
+	}
+
+	var candidates []string
+	for _, source := range sources {
+		if err := util.ValidateGitURL(source, g.trustedHosts); err != nil {
+			g.log.Warn(fmt.Sprintf("Skipping %s: %v", source, err))
+			continue
+		}
+		candidates = append(candidates, source)
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no usable URL for %s", config.URL)
+	}
+
 	
 	if files, err := os.ReadDir(expandedPath); err == nil && len(files) > 0 {
+		if remote, ok := g.existingRemote(expandedPath, sources); ok {
+			return g.Update(expandedPath, remote, config)
+		}
+
 		
 		timestamp := time.Now().Format("20060102_150405")
 		repoName := filepath.Base(expandedPath)
@@ -63,11 +161,11 @@ func (g *GitManager) Clone(config types.Repository) error {
 
 		
 		expandedPath = newPath
-		g.log.Warn(fmt.Sprintf("Original directory not empty, redirecting clone to: %s", expandedPath))
+		g.log.Warn(fmt.Sprintf("Original directory has foreign content, redirecting clone to: %s", expandedPath))
 
 		
 		metadataPath := filepath.Join(failedDir, fmt.Sprintf("%s_%s.txt", repoName, timestamp))
-		metadata := fmt.Sprintf("Original Path: %s\nRedirected Path: %s\nURL: %s\nBranch: %s\nDepth: %d\nReason: Original directory not empty\n",
+		metadata := fmt.Sprintf("Original Path: %s\nRedirected Path: %s\nURL: %s\nBranch: %s\nDepth: %d\nReason: Original directory not empty and not a clone of this repository\n",
 			config.Path, expandedPath, config.URL, config.Branch, config.Depth)
 
 		if err := os.WriteFile(metadataPath, []byte(metadata), 0644); err != nil {
@@ -75,33 +173,267 @@ func (g *GitManager) Clone(config types.Repository) error {
 		}
 	}
 
-	
-	parsedURL, err := url.Parse(config.URL)
+	parentDir := filepath.Dir(expandedPath)
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", parentDir, err)
+	}
+
+	var cloneErrors []string
+	clonedFrom := ""
+	for _, source := range candidates {
+		if err := g.cloneFrom(source, expandedPath, config); err != nil {
+			g.log.Warn(fmt.Sprintf("Clone from %s failed, trying next source", source))
+			cloneErrors = append(cloneErrors, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+		clonedFrom = source
+		break
+	}
+	if clonedFrom == "" {
+		return fmt.Errorf("git clone failed:\n%s", strings.Join(cloneErrors, "\n"))
+	}
+
+	if err := g.checkoutOptions(expandedPath, clonedFrom, config); err != nil {
+		return err
+	}
+
+	if config.SubmoduleInit {
+		if err := g.updateSubmodules(expandedPath, clonedFrom); err != nil {
+			return err
+		}
+	}
+
+	if err := g.syncWorktrees(expandedPath, clonedFrom, config); err != nil {
+		return err
+	}
+
+	if err := g.runHooks(expandedPath, config, true); err != nil {
+		return err
+	}
+
+	g.log.Success(fmt.Sprintf("Successfully cloned repository to %s", expandedPath))
+	return nil
+}
+
+func (g *GitManager) Update(path, remote string, config types.Repository) error {
+	policy := strings.ToLower(config.Update)
+	if policy == "" {
+		policy = "ff-only"
+	}
+
+	if policy == "none" {
+		g.log.Info(fmt.Sprintf("%s is already cloned, updates disabled", path))
+		if err := g.syncWorktrees(path, remote, config); err != nil {
+			return err
+		}
+		return g.runHooks(path, config, false)
+	}
+	if policy != "ff-only" && policy != "rebase" {
+		return fmt.Errorf("unsupported update policy: %s", config.Update)
+	}
+
+	g.log.Info(fmt.Sprintf("Fetching updates for %s", path))
+	if _, err := g.runGit(remote, "-C", path, "fetch", "--prune", "origin"); err != nil {
+		return fmt.Errorf("git fetch failed: %w", err)
+	}
+
+	if config.Tag != "" || config.Commit != "" {
+		if err := g.checkoutOptions(path, remote, config); err != nil {
+			return err
+		}
+		if config.SubmoduleInit {
+			if err := g.updateSubmodules(path, remote); err != nil {
+				return err
+			}
+		}
+		if err := g.syncWorktrees(path, remote, config); err != nil {
+			return err
+		}
+		if err := g.runHooks(path, config, false); err != nil {
+			return err
+		}
+		g.log.Success(fmt.Sprintf("Updated %s (pinned)", path))
+		return nil
+	}
+
+	current, err := g.runGit("", "-C", path, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to determine current branch: %w", err)
+	}
+
+	branch := config.Branch
+	if branch == "" {
+		branch = current
+	}
+	if branch == "HEAD" {
+		g.log.Warn(fmt.Sprintf("%s has a detached HEAD, skipping update", path))
+		return g.syncWorktrees(path, remote, config)
+	}
+
+	upstream := "origin/" + branch
+	switch {
+	case branch != current && policy == "ff-only":
+		
+		if _, err := g.runGit(remote, "-C", path, "fetch", "origin", branch+":"+branch); err != nil {
+			return fmt.Errorf("failed to fast-forward %s: %w", branch, err)
+		}
+	case branch != current:
+		g.log.Warn(fmt.Sprintf("%s is not checked out in %s, skipping rebase", branch, path))
+		return g.syncWorktrees(path, remote, config)
+	case policy == "ff-only":
+		if _, err := g.runGit("", "-C", path, "merge", "--ff-only", upstream); err != nil {
+			return fmt.Errorf("failed to fast-forward %s: %w", branch, err)
+		}
+	default:
+		if _, err := g.runGit("", "-C", path, "rebase", upstream); err != nil {
+			g.runGit("", "-C", path, "rebase", "--abort")
+			return fmt.Errorf("failed to rebase %s onto %s: %w", branch, upstream, err)
+		}
+	}
+
+	if err := g.checkoutOptions(path, remote, config); err != nil {
+		return err
+	}
+
+	if config.SubmoduleInit {
+		if err := g.updateSubmodules(path, remote); err != nil {
+			return err
+		}
+	}
+
+	if err := g.syncWorktrees(path, remote, config); err != nil {
+		return err
+	}
+
+	if err := g.runHooks(path, config, false); err != nil {
+		return err
+	}
+
+	g.log.Success(fmt.Sprintf("Updated %s (%s)", path, policy))
+	return nil
+}
+
+
+func (g *GitManager) existingRemote(path string, sources []string) (string, bool) {
+	topLevel, err := g.runGit("", "-C", path, "rev-parse", "--show-toplevel")
+	if err != nil || !samePath(topLevel, path) {
+		return "", false
+	}
+
+	remote, err := g.runGit("", "-C", path, "remote", "get-url", "origin")
 	if err != nil {
-		return fmt.Errorf("invalid git URL: %w", err)
+		return "", false
 	}
 
-	if parsedURL.Scheme != "https" {
-		return fmt.Errorf("only HTTPS URLs are allowed")
+	for _, source := range sources {
+		if normalizeRemote(source) == normalizeRemote(remote) {
+			return remote, true
+		}
 	}
+	return "", false
+}
 
-	host := strings.ToLower(parsedURL.Host)
-	trustedHosts := map[string]bool{
-		"github.com":    true,
-		"gitlab.com":    true,
-		"bitbucket.org": true,
-		"dev.azure.com": true,
+func normalizeRemote(remote string) string {
+	remote = strings.ToLower(strings.TrimSpace(remote))
+	remote = strings.TrimSuffix(remote, "/")
+	remote = strings.TrimSuffix(remote, ".git")
+	remote = strings.TrimPrefix(remote, "ssh://")
+	remote = strings.TrimPrefix(remote, "https://")
+	if at := strings.Index(remote, "@"); at >= 0 && !strings.Contains(remote[:at], "/") {
+		remote = remote[at+1:]
 	}
+	return strings.Replace(remote, ":", "/", 1)
+}
 
-	if !trustedHosts[host] {
-		return fmt.Errorf("untrusted Git host: %s", host)
+func samePath(a, b string) bool {
+	a, errA := filepath.Abs(filepath.FromSlash(a))
+	b, errB := filepath.Abs(filepath.FromSlash(b))
+	return errA == nil && errB == nil && strings.EqualFold(filepath.Clean(a), filepath.Clean(b))
+}
+
+
+func (g *GitManager) checkoutOptions(path, remote string, config types.Repository) error {
+	if len(config.Sparse) > 0 {
+		g.log.Info(fmt.Sprintf("Setting sparse-checkout patterns for %s", path))
+		args := append([]string{"-C", path, "sparse-checkout", "set", "--cone"}, config.Sparse...)
+		if _, err := g.runGit(remote, args...); err != nil {
+			return fmt.Errorf("sparse-checkout failed: %w", err)
+		}
 	}
 
-	parentDir := filepath.Dir(expandedPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", parentDir, err)
+	if config.Tag != "" || config.Commit != "" {
+		ref := config.Commit
+		if ref == "" {
+			ref = "refs/tags/" + config.Tag
+		}
+
+		fetchArgs := []string{"-C", path, "fetch"}
+		if config.Depth > 0 {
+			fetchArgs = append(fetchArgs, "--depth", fmt.Sprintf("%d", config.Depth))
+		}
+		if config.Tag != "" && config.Commit == "" {
+			fetchArgs = append(fetchArgs, "origin", ref+":"+ref)
+		} else {
+			fetchArgs = append(fetchArgs, "origin", ref)
+		}
+		if _, err := g.runGit(remote, fetchArgs...); err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", ref, err)
+		}
+
+		if _, err := g.runGit("", "-C", path, "checkout", "--detach", ref); err != nil {
+			return fmt.Errorf("failed to check out %s: %w", ref, err)
+		}
+		g.log.Info(fmt.Sprintf("Checked out %s at %s", path, ref))
+	}
+
+	if config.LFS {
+		g.log.Info(fmt.Sprintf("Fetching Git LFS objects for %s", path))
+		if _, err := g.runGit("", "-C", path, "lfs", "install", "--local"); err != nil {
+			return fmt.Errorf("git lfs install failed: %w", err)
+		}
+		if _, err := g.runGit(remote, "-C", path, "lfs", "pull"); err != nil {
+			return fmt.Errorf("git lfs pull failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (g *GitManager) updateSubmodules(path, remote string) error {
+	g.log.Info("Initializing submodules")
+	if _, err := g.runGit(remote, "-C", path,
+		"-c", "protocol.version=2",
+		"-c", "transfer.fsckObjects=true",
+		"-c", "fetch.fsckObjects=true",
+		"submodule", "update", "--init", "--recursive"); err != nil {
+		return fmt.Errorf("submodule initialization failed: %w", err)
+	}
+	return nil
+}
+
+
+func (g *GitManager) runGit(remote string, args ...string) (string, error) {
+	env := g.gitEnv()
+	if remote != "" {
+		proxyEnv, err := g.proxy.gitEnv(remote)
+		if err != nil {
+			return "", err
+		}
+		args = append(g.proxy.gitArgs(), args...)
+		env = append(env, proxyEnv...)
 	}
 
+	cmd := exec.Command("git", args...)
+	cmd.Env = env
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s", g.proxy.redact(strings.TrimSpace(string(output))))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (g *GitManager) cloneFrom(source, path string, config types.Repository) error {
 	args := []string{"clone"}
 
 	
@@ -111,45 +443,36 @@ func (g *GitManager) Clone(config types.Repository) error {
 		"--config", "fetch.fsckObjects=true",
 	)
 
-	if config.Branch != "" {
+	switch {
+	case config.Tag != "":
+		args = append(args, "-b", config.Tag)
+	case config.Branch != "":
 		args = append(args, "-b", config.Branch)
 	}
 	if config.Depth > 0 {
 		args = append(args, "--depth", fmt.Sprintf("%d", config.Depth))
 	}
+	if config.Filter != "" {
+		args = append(args, "--filter="+config.Filter)
+	}
+	if len(config.Sparse) > 0 {
+		args = append(args, "--sparse")
+	}
 
-	args = append(args, config.URL, expandedPath)
-
-	g.log.Info(fmt.Sprintf("Cloning %s into %s", config.URL, expandedPath))
+	args = append(args, source, path)
 
-	cmd := exec.Command("git", args...)
-	cmd.Env = append(os.Environ(),
-		"GIT_TERMINAL_PROMPT=0",
-		"GIT_SSL_NO_VERIFY=false",
-	)
-
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git clone failed: %s", string(output))
+	proxyEnv, err := g.proxy.gitEnv(source)
+	if err != nil {
+		return err
 	}
 
-	if config.SubmoduleInit {
-		g.log.Info("Initializing submodules")
-		cmd = exec.Command("git", "-C", expandedPath, "submodule", "update",
-			"--init", "--recursive",
-			"--config", "protocol.version=2",
-			"--config", "transfer.fsckObjects=true",
-			"--config", "fetch.fsckObjects=true")
+	g.log.Info(fmt.Sprintf("Cloning %s into %s", source, path))
 
-		cmd.Env = append(os.Environ(),
-			"GIT_TERMINAL_PROMPT=0",
-			"GIT_SSL_NO_VERIFY=false",
-		)
+	cmd := exec.Command("git", append(g.proxy.gitArgs(), args...)...)
+	cmd.Env = append(g.gitEnv(), proxyEnv...)
 
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("submodule initialization failed: %s", string(output))
-		}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s", g.proxy.redact(strings.TrimSpace(string(output))))
 	}
-
-	g.log.Success(fmt.Sprintf("Successfully cloned repository to %s", expandedPath))
 	return nil
 }
@@ -0,0 +1,120 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cat2/liftoff/types"
+	"cat2/liftoff/util"
+)
+
+var nonAlnum = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func (g *GitManager) configureGlobal(config types.GitConfig) error {
+	if len(config.Config) == 0 && len(config.Includes) == 0 {
+		return nil
+	}
+
+	g.log.Info("Configuring global git settings")
+
+	if err := g.applyGitConfig(nil, config.Config); err != nil {
+		return err
+	}
+
+	for _, include := range config.Includes {
+		if include.GitDir == "" {
+			return fmt.Errorf("git include is missing gitdir")
+		}
+
+		gitDir := filepath.ToSlash(os.ExpandEnv(include.GitDir))
+		if !strings.HasSuffix(gitDir, "/") {
+			gitDir += "/"
+		}
+
+		includePath := os.ExpandEnv(include.Path)
+		if includePath == "" {
+			name := strings.Trim(nonAlnum.ReplaceAllString(strings.ToLower(gitDir), "-"), "-")
+			includePath = filepath.Join(os.ExpandEnv("${USERPROFILE}"), ".gitconfig-liftoff-"+name)
+		}
+
+		if err := g.applyGitConfig([]string{"--file", includePath}, include.Config); err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("includeIf.gitdir/i:%s.path", gitDir)
+		if err := g.applyGitConfig(nil, map[string]types.GitConfigValue{key: {filepath.ToSlash(includePath)}}); err != nil {
+			return err
+		}
+	}
+
+	g.log.Success("Global git settings are up to date")
+	return nil
+}
+
+
+func (g *GitManager) applyGitConfig(scope []string, values map[string]types.GitConfigValue) error {
+	if scope == nil {
+		scope = []string{"--global"}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		desired := []string(values[key])
+
+		
+		getArgs := append(append([]string{"config", "-z"}, scope...), "--get-all", key)
+		current, _ := exec.Command("git", getArgs...).Output()
+		existing := util.ParseGitConfigValues(current)
+
+		if util.GitConfigEqual(existing, desired) {
+			continue
+		}
+		g.log.Info(fmt.Sprintf("git config %s: %s -> %s", key, gitConfigList(existing), gitConfigList(desired)))
+
+		var commands [][]string
+		switch {
+		case len(desired) == 0:
+			commands = append(commands, []string{"--unset-all", key})
+		default:
+			commands = append(commands, []string{"--replace-all", key, desired[0]})
+			for _, value := range desired[1:] {
+				commands = append(commands, []string{"--add", key, value})
+			}
+		}
+
+		for _, command := range commands {
+			args := append(append([]string{"config"}, scope...), command...)
+			if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+				g.log.Error(fmt.Sprintf("Failed to set git config %s", key))
+				return fmt.Errorf("failed to set git config %s: %s", key, strings.TrimSpace(string(output)))
+			}
+		}
+	}
+
+	return nil
+}
+
+func gitConfigList(values []string) string {
+	switch len(values) {
+	case 0:
+		return "(unset)"
+	case 1:
+		return fmt.Sprintf("%q", values[0])
+	default:
+		quoted := make([]string, len(values))
+		for i, value := range values {
+			quoted[i] = fmt.Sprintf("%q", value)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+}
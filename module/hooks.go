@@ -0,0 +1,159 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"cat2/liftoff/types"
+)
+
+const (
+	defaultHookTimeout = 10 * time.Minute
+	hookWaitDelay      = 10 * time.Second
+)
+
+func (g *GitManager) UseEnvironment(config types.EnvironmentConfig) {
+	g.environment = config
+}
+
+func (g *GitManager) runHooks(path string, config types.Repository, firstClone bool) error {
+	for _, hook := range config.PostClone {
+		if !firstClone && !hook.Always {
+			continue
+		}
+		if err := g.runHook(path, config, hook, firstClone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GitManager) runHook(path string, config types.Repository, hook types.Hook, firstClone bool) error {
+	timeout := defaultHookTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	g.log.Info(fmt.Sprintf("Running post-clone command in %s: %s", path, hook.Command))
+
+	cmd := exec.CommandContext(ctx, "cmd", "/C", hook.Command)
+	cmd.Dir = path
+	cmd.Env = g.hookEnv(path, config, firstClone)
+	
+	cmd.Cancel = func() error {
+		exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = hookWaitDelay
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		g.log.Error(fmt.Sprintf("Post-clone command timed out after %s: %s", timeout, hook.Command))
+		return fmt.Errorf("post-clone command %q timed out after %s", hook.Command, timeout)
+	}
+	if err != nil {
+		g.log.Error(fmt.Sprintf("Post-clone command failed: %s", hook.Command))
+		g.log.Error(strings.TrimSpace(string(output)))
+		return fmt.Errorf("post-clone command %q failed: %w", hook.Command, err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			g.log.Debug(strings.TrimRight(line, "\r"))
+		}
+	}
+	g.log.Success(fmt.Sprintf("Post-clone command finished: %s", hook.Command))
+	return nil
+}
+
+func (g *GitManager) hookEnv(path string, config types.Repository, firstClone bool) []string {
+	vars := make(map[string]string)
+	var order []string
+	set := func(name, value string) {
+		key := strings.ToUpper(name)
+		if _, ok := vars[key]; !ok {
+			order = append(order, key)
+		}
+		vars[key] = name + "=" + value
+	}
+	lookup := func(name string) string {
+		_, value, _ := strings.Cut(vars[strings.ToUpper(name)], "=")
+		return value
+	}
+
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok && name != "" {
+			set(name, value)
+		}
+	}
+	for name, variable := range g.environment.Variables {
+		set(name, os.ExpandEnv(variable.Value))
+	}
+	for _, entry := range g.environment.Unset {
+		delete(vars, strings.ToUpper(entry.Name))
+	}
+
+	
+	remove := make(map[string]bool)
+	for _, p := range g.environment.PathRemove {
+		remove[normalizePathEntry(expandPercent(os.ExpandEnv(p), lookup))] = true
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(entry string) {
+		entry = expandPercent(entry, lookup)
+		normalized := normalizePathEntry(entry)
+		if entry == "" || seen[normalized] || remove[normalized] {
+			return
+		}
+		seen[normalized] = true
+		paths = append(paths, entry)
+	}
+	for _, p := range g.environment.PathPrepend {
+		add(os.ExpandEnv(p))
+	}
+	for _, p := range strings.Split(lookup("PATH"), ";") {
+		add(p)
+	}
+	for _, p := range g.environment.PathAppend {
+		add(os.ExpandEnv(p))
+	}
+	set("PATH", strings.Join(paths, ";"))
+
+	env := make([]string, 0, len(order))
+	for _, key := range order {
+		if entry, ok := vars[key]; ok {
+			env = append(env, entry)
+		}
+	}
+
+	first := "0"
+	if firstClone {
+		first = "1"
+	}
+	env = append(env,
+		"LIFTOFF_REPO_PATH="+path,
+		"LIFTOFF_REPO_URL="+config.URL,
+		"LIFTOFF_REPO_BRANCH="+config.Branch,
+		"LIFTOFF_FIRST_CLONE="+first,
+	)
+	return env
+}
+
+
+func expandPercent(value string, lookup func(string) string) string {
+	return envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		if resolved := lookup(strings.Trim(ref, "%")); resolved != "" {
+			return resolved
+		}
+		return ref
+	})
+}
@@ -6,11 +6,27 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 )
 
+const (
+	defaultHostsPath = `C:\Windows\System32\drivers\etc\hosts`
+
+	internetSettingsPath          = `Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+	internetOptionSettingsChanged = 39
+	internetOptionRefresh         = 37
+)
+
+var (
+	wininet                = windows.NewLazySystemDLL("wininet.dll")
+	procInternetSetOptionW = wininet.NewProc("InternetSetOptionW")
+)
+
 type NetworkManager struct {
 	log *util.Logger
 }
@@ -23,109 +39,95 @@ func NewNetworkManager(log *util.Logger) *NetworkManager {
 
 func (n *NetworkManager) Configure(config types.NetworkConfig) error {
 	
-	if len(config.DNSServers) > 0 {
-		if err := n.setDNSServers(config.DNSServers); err != nil {
+	if len(config.DNSServers) > 0 || len(config.DNS) > 0 {
+		rules := config.DNS
+		if len(config.DNSServers) > 0 {
+			rules = append(rules, types.DNSRule{Servers: config.DNSServers})
+		}
+		if err := n.configureDNS(rules); err != nil {
 			return err
 		}
 	}
 
 	
-	if len(config.HostsEntries) > 0 {
-		if err := n.updateHostsFile(config.HostsEntries); err != nil {
-			return err
-		}
+	if err := n.updateHostsFile(config.HostsEntries, config.HostsPath); err != nil {
+		return err
 	}
 
 	
-	if config.Proxy.Enable {
+	if config.Proxy.Present() {
 		if err := n.setProxy(config.Proxy); err != nil {
 			return err
 		}
 	}
 
+	
+	if err := n.configureFirewall(config.Firewall); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (n *NetworkManager) setDNSServers(servers []string) error {
-	n.log.Info("Setting DNS servers")
+func (n *NetworkManager) updateHostsFile(entries map[string]types.HostAddrs, hostsPath string) error {
+	if hostsPath == "" {
+		hostsPath = defaultHostsPath
+	}
 
 	
-	cmd := exec.Command("netsh", "interface", "show", "interface")
-	output, err := cmd.Output()
+	content, err := os.ReadFile(hostsPath)
 	if err != nil {
-		n.log.Error("Failed to get network interfaces")
-		return fmt.Errorf("failed to get network interfaces: %w", err)
+		n.log.Error("Failed to read hosts file")
+		return fmt.Errorf("failed to read hosts file: %w", err)
 	}
+	text := string(content)
 
-	
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "Enabled") {
-			fields := strings.Fields(line)
-			if len(fields) >= 4 {
-				interfaceName := strings.Join(fields[3:], " ")
-				
-				dnsCmd := exec.Command("netsh", "interface", "ipv4", "set", "dns",
-					interfaceName, "static", servers[0])
-				if err := dnsCmd.Run(); err != nil {
-					n.log.Error(fmt.Sprintf("Failed to set primary DNS for %s", interfaceName))
-					return fmt.Errorf("failed to set DNS: %w", err)
-				}
-
-				
-				for i, server := range servers[1:] {
-					addCmd := exec.Command("netsh", "interface", "ipv4", "add", "dns",
-						interfaceName, server, fmt.Sprintf("index=%d", i+2))
-					if err := addCmd.Run(); err != nil {
-						n.log.Error(fmt.Sprintf("Failed to add DNS server %s", server))
-						return fmt.Errorf("failed to add DNS server: %w", err)
-					}
-				}
-			}
-		}
+	desired := make(map[string][]string, len(entries))
+	for hostname, ips := range entries {
+		desired[strings.ToLower(hostname)] = append(desired[strings.ToLower(hostname)], ips...)
 	}
 
-	n.log.Success("Successfully configured DNS servers")
-	return nil
-}
-
-func (n *NetworkManager) updateHostsFile(entries map[string]string) error {
-	n.log.Info("Updating hosts file")
+	current, unmanaged := util.ParseHostsFile(text, managedBlockBegin, managedBlockEnd)
+	if len(entries) == 0 && len(current) == 0 && !strings.Contains(text, managedBlockBegin) {
+		return nil
+	}
 
-	hostsPath := `C:\Windows\System32\drivers\etc\hosts`
+	n.log.Info(fmt.Sprintf("Updating hosts file %s", hostsPath))
 
-	
-	content, err := os.ReadFile(hostsPath)
+	block, err := util.HostsBlock(desired, managedBlockBegin, managedBlockEnd)
 	if err != nil {
-		n.log.Error("Failed to read hosts file")
-		return fmt.Errorf("failed to read hosts file: %w", err)
+		n.log.Error("Invalid hosts entry")
+		return err
 	}
 
 	
-	lines := strings.Split(string(content), "\n")
-	existingEntries := make(map[string]bool)
-	newLines := make([]string, 0)
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				existingEntries[fields[1]] = true
-			}
+	for _, hostname := range sortedKeys(desired) {
+		ips := strings.Join(desired[hostname], ", ")
+		switch old, ok := current[hostname]; {
+		case !ok:
+			n.log.Success(fmt.Sprintf("Added %s -> %s", hostname, ips))
+		case !util.SameHostAddrs(old, desired[hostname]):
+			n.log.Success(fmt.Sprintf("Changed %s: %s -> %s", hostname, strings.Join(old, ", "), ips))
+		}
+		if unmanaged[hostname] {
+			n.log.Warn(fmt.Sprintf("%s is also defined outside the Liftoff block", hostname))
 		}
-		newLines = append(newLines, line)
 	}
-
-	
-	for hostname, ip := range entries {
-		if !existingEntries[hostname] {
-			newLines = append(newLines, fmt.Sprintf("%s\t%s", ip, hostname))
+	for _, hostname := range sortedKeys(current) {
+		if _, ok := desired[hostname]; !ok {
+			n.log.Success(fmt.Sprintf("Removed %s", hostname))
 		}
 	}
 
-	
-	if err := os.WriteFile(hostsPath, []byte(strings.Join(newLines, "\n")), 0644); err != nil {
+	updated := util.ReplaceManagedBlock(text, block, managedBlockBegin, managedBlockEnd)
+	if strings.Contains(text, "\r\n") {
+		updated = strings.ReplaceAll(updated, "\n", "\r\n")
+	}
+	if updated == text {
+		return nil
+	}
+
+	if err := writeFileAtomic(hostsPath, []byte(updated)); err != nil {
 		n.log.Error("Failed to write hosts file")
 		return fmt.Errorf("failed to write hosts file: %w", err)
 	}
@@ -134,31 +136,168 @@ func (n *NetworkManager) updateHostsFile(entries map[string]string) error {
 	return nil
 }
 
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (n *NetworkManager) setProxy(config types.ProxyConfig) error {
 	n.log.Info("Configuring proxy settings")
 
-	key, err := registry.OpenKey(registry.CURRENT_USER,
-		`Software\Microsoft\Windows\CurrentVersion\Internet Settings`,
-		registry.ALL_ACCESS)
+	key, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsPath, registry.ALL_ACCESS)
 	if err != nil {
 		n.log.Error("Failed to open registry key")
 		return fmt.Errorf("failed to open registry key: %w", err)
 	}
 	defer key.Close()
 
-	
-	if err := key.SetDWordValue("ProxyEnable", uint32(1)); err != nil {
-		n.log.Error("Failed to enable proxy")
-		return fmt.Errorf("failed to enable proxy: %w", err)
+	var server, bypass, pacURL string
+	if config.Enable {
+		if config.Server != "" {
+			server = fmt.Sprintf("%s:%d", config.Server, config.Port)
+		}
+		bypass = strings.Join(config.Bypass, ";")
+		pacURL = config.PACURL
+	}
+
+	enable := uint32(0)
+	if server != "" {
+		enable = 1
+	}
+	if err := n.setInternetSetting(key, "ProxyEnable", enable); err != nil {
+		return err
+	}
+	if server != "" {
+		if err := n.setInternetSetting(key, "ProxyServer", server); err != nil {
+			return err
+		}
+	}
+	if err := n.setInternetSetting(key, "ProxyOverride", bypass); err != nil {
+		return err
+	}
+	if err := n.setInternetSetting(key, "AutoConfigURL", pacURL); err != nil {
+		return err
 	}
+	refreshInternetSettings()
 
 	
-	proxyServer := fmt.Sprintf("%s:%d", config.Server, config.Port)
-	if err := key.SetStringValue("ProxyServer", proxyServer); err != nil {
-		n.log.Error("Failed to set proxy server")
-		return fmt.Errorf("failed to set proxy server: %w", err)
+	if server != "" && config.Username != "" {
+		if err := n.storeProxyCredentials(config); err != nil {
+			return err
+		}
 	}
 
-	n.log.Success("Successfully configured proxy settings")
+	if err := n.setWinHTTPProxy(server, bypass); err != nil {
+		return err
+	}
+
+	if config.Enable {
+		n.log.Success("Successfully configured proxy settings")
+	} else {
+		n.log.Success("Proxy disabled")
+	}
+	return nil
+}
+
+
+func (n *NetworkManager) setInternetSetting(key registry.Key, name string, value interface{}) error {
+	var err error
+	switch v := value.(type) {
+	case uint32:
+		current, _, getErr := key.GetIntegerValue(name)
+		if getErr == nil && current == uint64(v) {
+			return nil
+		}
+		n.log.Info(fmt.Sprintf("%s: %d -> %d", name, current, v))
+		err = key.SetDWordValue(name, v)
+	case string:
+		current, _, getErr := key.GetStringValue(name)
+		if getErr == registry.ErrNotExist && v == "" {
+			return nil
+		}
+		if getErr == nil && current == v {
+			return nil
+		}
+		n.log.Info(fmt.Sprintf("%s: %q -> %q", name, current, v))
+		if v == "" {
+			err = key.DeleteValue(name)
+		} else {
+			err = key.SetStringValue(name, v)
+		}
+	}
+
+	if err != nil {
+		n.log.Error(fmt.Sprintf("Failed to set %s", name))
+		return fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	return nil
+}
+
+func refreshInternetSettings() {
+	procInternetSetOptionW.Call(0, internetOptionSettingsChanged, 0, 0)
+	procInternetSetOptionW.Call(0, internetOptionRefresh, 0, 0)
+}
+
+func (n *NetworkManager) storeProxyCredentials(config types.ProxyConfig) error {
+	username, err := util.ResolveSecret(config.Username)
+	if err != nil {
+		return fmt.Errorf("proxy username: %w", err)
+	}
+	password, err := util.ResolveSecret(config.Password)
+	if err != nil {
+		return fmt.Errorf("proxy password: %w", err)
+	}
+
+	if err := storeCredential(config.Server, username, password); err != nil {
+		n.log.Error("Failed to store proxy credentials")
+		return fmt.Errorf("failed to store proxy credentials for %s: %w", config.Server, err)
+	}
+	n.log.Success(fmt.Sprintf("Stored proxy credentials for %s in the Credential Manager", config.Server))
+	return nil
+}
+
+
+func (n *NetworkManager) setWinHTTPProxy(server, bypass string) error {
+	args := []string{"winhttp", "reset", "proxy"}
+	if server != "" {
+		args = []string{"winhttp", "set", "proxy", "proxy-server=" + server}
+		if bypass != "" {
+			args = append(args, "bypass-list="+bypass)
+		}
+	}
+
+	cmd := exec.Command("netsh", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		n.log.Error("Failed to configure WinHTTP proxy")
+		return fmt.Errorf("failed to configure WinHTTP proxy: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	n.log.Info("Applied proxy settings to WinHTTP")
 	return nil
 }
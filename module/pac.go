@@ -0,0 +1,87 @@
+package module
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	winhttpAccessTypeNoProxy  = 1
+	winhttpAutoproxyConfigURL = 0x00000002
+)
+
+var (
+	winhttp                   = windows.NewLazySystemDLL("winhttp.dll")
+	procWinHttpOpen           = winhttp.NewProc("WinHttpOpen")
+	procWinHttpCloseHandle    = winhttp.NewProc("WinHttpCloseHandle")
+	procWinHttpGetProxyForUrl = winhttp.NewProc("WinHttpGetProxyForUrl")
+
+	kernel32       = windows.NewLazySystemDLL("kernel32.dll")
+	procGlobalFree = kernel32.NewProc("GlobalFree")
+)
+
+type winhttpAutoproxyOptions struct {
+	flags                 uint32
+	autoDetectFlags       uint32
+	autoConfigURL         *uint16
+	reserved              uintptr
+	reservedSize          uint32
+	autoLogonIfChallenged int32
+}
+
+type winhttpProxyInfo struct {
+	accessType  uint32
+	proxy       *uint16
+	proxyBypass *uint16
+}
+
+func resolvePAC(pacURL, target string) (string, error) {
+	agent, err := windows.UTF16PtrFromString("Liftoff")
+	if err != nil {
+		return "", err
+	}
+
+	session, _, callErr := procWinHttpOpen.Call(uintptr(unsafe.Pointer(agent)), winhttpAccessTypeNoProxy, 0, 0, 0)
+	if session == 0 {
+		return "", fmt.Errorf("WinHttpOpen: %w", callErr)
+	}
+	defer procWinHttpCloseHandle.Call(session)
+
+	targetPtr, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return "", err
+	}
+	pacPtr, err := windows.UTF16PtrFromString(pacURL)
+	if err != nil {
+		return "", err
+	}
+
+	options := winhttpAutoproxyOptions{
+		flags:                 winhttpAutoproxyConfigURL,
+		autoConfigURL:         pacPtr,
+		autoLogonIfChallenged: 1,
+	}
+	var info winhttpProxyInfo
+
+	ok, _, callErr := procWinHttpGetProxyForUrl.Call(session,
+		uintptr(unsafe.Pointer(targetPtr)),
+		uintptr(unsafe.Pointer(&options)),
+		uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return "", fmt.Errorf("WinHttpGetProxyForUrl: %w", callErr)
+	}
+
+	if info.proxy != nil {
+		defer procGlobalFree.Call(uintptr(unsafe.Pointer(info.proxy)))
+	}
+	if info.proxyBypass != nil {
+		defer procGlobalFree.Call(uintptr(unsafe.Pointer(info.proxyBypass)))
+	}
+
+	if info.accessType == winhttpAccessTypeNoProxy || info.proxy == nil {
+		return "DIRECT", nil
+	}
+	return windows.UTF16PtrToString(info.proxy), nil
+}
@@ -0,0 +1,217 @@
+package module
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"cat2/liftoff/types"
+	"cat2/liftoff/util"
+)
+
+type proxyResolver struct {
+	config   types.ProxyConfig
+	fixed    *url.URL
+	bypass   []string
+	caBundle string
+}
+
+func newProxyResolver(config types.ProxyConfig) (*proxyResolver, error) {
+	p := &proxyResolver{config: config}
+
+	
+	if config.CABundle != "" {
+		p.caBundle = os.ExpandEnv(config.CABundle)
+		if _, err := os.Stat(p.caBundle); err != nil {
+			return nil, fmt.Errorf("CA bundle %s: %w", p.caBundle, err)
+		}
+	}
+
+	if !config.Enable {
+		return p, nil
+	}
+
+	for _, entry := range config.Bypass {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			p.bypass = append(p.bypass, entry)
+		}
+	}
+
+	if config.Server != "" {
+		fixed, err := p.proxyURL(fmt.Sprintf("%s:%d", config.Server, config.Port))
+		if err != nil {
+			return nil, err
+		}
+		p.fixed = fixed
+	}
+
+	return p, nil
+}
+
+func (p *proxyResolver) proxyURL(hostPort string) (*url.URL, error) {
+	raw := hostPort
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address %s: %w", hostPort, err)
+	}
+
+	if p.config.Username != "" {
+		username, err := util.ResolveSecret(p.config.Username)
+		if err != nil {
+			return nil, fmt.Errorf("proxy username: %w", err)
+		}
+		password, err := util.ResolveSecret(p.config.Password)
+		if err != nil {
+			return nil, fmt.Errorf("proxy password: %w", err)
+		}
+		proxyURL.User = url.UserPassword(username, password)
+	}
+
+	return proxyURL, nil
+}
+
+func (p *proxyResolver) bypassed(host string) bool {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	for _, entry := range p.bypass {
+		switch {
+		case entry == "<local>":
+			if !strings.Contains(host, ".") {
+				return true
+			}
+		case strings.HasPrefix(entry, "."):
+			if strings.HasSuffix(host, entry) || host == entry[1:] {
+				return true
+			}
+		default:
+			if matched, _ := path.Match(entry, host); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *proxyResolver) proxyFor(target *url.URL) (*url.URL, error) {
+	if !p.config.Enable || p.bypassed(target.Host) {
+		return nil, nil
+	}
+
+	if p.config.PACURL != "" {
+		proxies, err := resolvePAC(p.config.PACURL, target.String())
+		if err != nil {
+			return nil, fmt.Errorf("PAC evaluation failed: %w", err)
+		}
+		for _, proxy := range strings.FieldsFunc(proxies, func(r rune) bool { return r == ';' || r == ' ' }) {
+			if strings.EqualFold(proxy, "DIRECT") {
+				return nil, nil
+			}
+
+			if i := strings.Index(proxy, "="); i >= 0 {
+				if !strings.EqualFold(proxy[:i], target.Scheme) {
+					continue
+				}
+				proxy = proxy[i+1:]
+			}
+			return p.proxyURL(proxy)
+		}
+		return nil, nil
+	}
+
+	return p.fixed, nil
+}
+
+func (p *proxyResolver) transportProxy(req *http.Request) (*url.URL, error) {
+	return p.proxyFor(req.URL)
+}
+
+func (p *proxyResolver) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if p.caBundle == "" {
+		return config, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := os.ReadFile(p.caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", p.caBundle)
+	}
+
+	config.RootCAs = pool
+	return config, nil
+}
+
+
+func (p *proxyResolver) gitArgs() []string {
+	if p.caBundle == "" {
+		return nil
+	}
+	return []string{
+		"-c", "http.sslBackend=openssl",
+		"-c", "http.sslCAInfo=" + p.caBundle,
+	}
+}
+
+
+func (p *proxyResolver) gitEnv(target string) ([]string, error) {
+	if !p.config.Enable {
+		return nil, nil
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil || (targetURL.Scheme != "http" && targetURL.Scheme != "https") {
+		return nil, nil
+	}
+
+	proxy, err := p.proxyFor(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		return []string{"no_proxy=*", "NO_PROXY=*"}, nil
+	}
+
+	value := proxy.String()
+	return []string{
+		"http_proxy=" + value,
+		"https_proxy=" + value,
+		"HTTPS_PROXY=" + value,
+	}, nil
+}
+
+
+func (p *proxyResolver) redact(text string) string {
+	if !p.config.Enable || p.config.Password == "" {
+		return text
+	}
+	password, err := util.ResolveSecret(p.config.Password)
+	if err != nil || password == "" {
+		return text
+	}
+	escaped := strings.TrimPrefix(url.UserPassword("", password).String(), ":")
+	text = strings.ReplaceAll(text, escaped, "***")
+	return strings.ReplaceAll(text, password, "***")
+}
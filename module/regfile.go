@@ -0,0 +1,142 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"cat2/liftoff/util"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func (s *SystemConfigurator) ImportRegFile(path string) error {
+	expanded := os.ExpandEnv(path)
+	s.log.Info(fmt.Sprintf("Importing registry file: %s", expanded))
+
+	data, err := os.ReadFile(expanded)
+	if err != nil {
+		s.log.Error(fmt.Sprintf("Failed to read registry file: %s", expanded))
+		return fmt.Errorf("failed to read %s: %w", expanded, err)
+	}
+
+	keys, err := util.ParseRegFile(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", expanded, err)
+	}
+
+	entries, err := util.RegKeysToConfig(keys)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s: %w", expanded, err)
+	}
+
+	for _, entry := range entries {
+		if err := s.ApplyRegistry(entry); err != nil {
+			return fmt.Errorf("%s: %w", expanded, err)
+		}
+	}
+
+	
+	for _, key := range keys {
+		if key.Delete || len(key.Values) > 0 {
+			continue
+		}
+		root, subPath, _ := strings.Cut(key.Path, `\`)
+		rootKey, err := registryRoot(root)
+		if err != nil {
+			return fmt.Errorf("%s: %w", expanded, err)
+		}
+		k, _, err := registry.CreateKey(rootKey, subPath, registry.ALL_ACCESS)
+		if err != nil {
+			return fmt.Errorf("%s: failed to create registry key %s: %w", expanded, key.Path, err)
+		}
+		k.Close()
+	}
+
+	s.log.Success(fmt.Sprintf("Imported %d registry entries from %s", len(entries), expanded))
+	return nil
+}
+
+func (s *SystemConfigurator) ExportRegistry(keyPath, outPath string) error {
+	root, subPath, _ := strings.Cut(keyPath, `\`)
+	rootKey, err := registryRoot(root)
+	if err != nil {
+		return err
+	}
+
+	rootName := map[registry.Key]string{
+		registry.LOCAL_MACHINE:  "HKEY_LOCAL_MACHINE",
+		registry.CURRENT_USER:   "HKEY_CURRENT_USER",
+		registry.USERS:          "HKEY_USERS",
+		registry.CLASSES_ROOT:   "HKEY_CLASSES_ROOT",
+		registry.CURRENT_CONFIG: "HKEY_CURRENT_CONFIG",
+	}[rootKey]
+
+	s.log.Info(fmt.Sprintf("Exporting %s to %s", keyPath, outPath))
+
+	var keys []util.RegKey
+	if err := s.exportRegistryKey(rootKey, rootName, subPath, &keys); err != nil {
+		s.log.Error(fmt.Sprintf("Failed to export %s", keyPath))
+		return fmt.Errorf("failed to export %s: %w", keyPath, err)
+	}
+
+	if err := os.WriteFile(outPath, util.FormatRegFile(keys), 0644); err != nil {
+		s.log.Error(fmt.Sprintf("Failed to write %s", outPath))
+		return fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
+
+	s.log.Success(fmt.Sprintf("Exported %d keys to %s", len(keys), outPath))
+	return nil
+}
+
+func (s *SystemConfigurator) exportRegistryKey(root registry.Key, rootName, path string, keys *[]util.RegKey) error {
+	key, err := registry.OpenKey(root, path, registry.READ)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+
+	regKey := util.RegKey{Path: rootName + `\` + path}
+
+	names, err := key.ReadValueNames(-1)
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		n, valueType, err := key.GetValue(name, nil)
+		if err != nil && err != registry.ErrShortBuffer {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		switch valueType {
+		case util.RegSZ, util.RegExpandSZ, util.RegBinary, util.RegDWord, util.RegMultiSZ, util.RegQWord:
+		default:
+			s.log.Warn(fmt.Sprintf("Skipping %s\\%s: unsupported value type %d", path, name, valueType))
+			continue
+		}
+
+		data := make([]byte, n)
+		if n > 0 {
+			if _, _, err := key.GetValue(name, data); err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+		}
+		regKey.Values = append(regKey.Values, util.RegValue{Name: name, Type: valueType, Data: data})
+	}
+	*keys = append(*keys, regKey)
+
+	subKeys, err := key.ReadSubKeyNames(-1)
+	if err != nil {
+		return err
+	}
+	sort.Strings(subKeys)
+
+	for _, sub := range subKeys {
+		if err := s.exportRegistryKey(root, rootName, path+`\`+sub, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
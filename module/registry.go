@@ -0,0 +1,187 @@
+package module
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"cat2/liftoff/types"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func registryRoot(name string) (registry.Key, error) {
+	switch strings.ToUpper(name) {
+	case "HKEY_LOCAL_MACHINE", "HKLM":
+		return registry.LOCAL_MACHINE, nil
+	case "HKEY_CURRENT_USER", "HKCU":
+		return registry.CURRENT_USER, nil
+	case "HKEY_USERS", "HKU":
+		return registry.USERS, nil
+	case "HKEY_CLASSES_ROOT", "HKCR":
+		return registry.CLASSES_ROOT, nil
+	case "HKEY_CURRENT_CONFIG", "HKCC":
+		return registry.CURRENT_CONFIG, nil
+	default:
+		return 0, fmt.Errorf("invalid registry root: %s", name)
+	}
+}
+
+func registryEntryName(config types.RegistryConfig) string {
+	name := config.Root + `\` + config.Path
+	if config.Name != "" {
+		name += `\` + config.Name
+	}
+	return fmt.Sprintf("registry %s", name)
+}
+
+
+func registryValueName(name string) string {
+	if name == "@" {
+		return ""
+	}
+	return name
+}
+
+
+func registryKeyPath(path string) (string, error) {
+	trimmed := strings.Trim(strings.TrimSpace(path), `\`)
+	if trimmed == "" {
+		return "", fmt.Errorf("refusing to modify the root of a registry hive")
+	}
+	return trimmed, nil
+}
+
+func deleteRegistryTree(root registry.Key, path string) error {
+	path, err := registryKeyPath(path)
+	if err != nil {
+		return err
+	}
+
+	key, err := registry.OpenKey(root, path, registry.ENUMERATE_SUB_KEYS|registry.QUERY_VALUE)
+	if err != nil {
+		return err
+	}
+	subKeys, err := key.ReadSubKeyNames(-1)
+	key.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subKeys {
+		if err := deleteRegistryTree(root, path+`\`+sub); err != nil {
+			return err
+		}
+	}
+	return registry.DeleteKey(root, path)
+}
+
+func registryString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	case int, int64, uint32, uint64, float64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("expected a string, got %T", value)
+	}
+}
+
+func registryStrings(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case string:
+		return strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n"), nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for i, item := range v {
+			str, err := registryString(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			result = append(result, str)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("expected a list of strings, got %T", value)
+	}
+}
+
+func registryInteger(value interface{}, bits int) (uint64, error) {
+	limit := uint64(math.MaxUint64)
+	if bits < 64 {
+		limit = 1<<uint(bits) - 1
+	}
+
+	var result uint64
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return 0, fmt.Errorf("value %d is negative", v)
+		}
+		result = uint64(v)
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("value %d is negative", v)
+		}
+		result = uint64(v)
+	case uint32:
+		result = uint64(v)
+	case uint64:
+		result = v
+	case float64:
+		if v < 0 || v != math.Trunc(v) || v > float64(limit) {
+			return 0, fmt.Errorf("value %v is not a valid %d-bit integer", v, bits)
+		}
+		result = uint64(v)
+	case string:
+		str := strings.TrimSpace(v)
+		base := 10
+		if strings.HasPrefix(strings.ToLower(str), "0x") {
+			str, base = str[2:], 16
+		}
+		parsed, err := strconv.ParseUint(str, base, bits)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %d-bit integer %q", bits, v)
+		}
+		result = parsed
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", value)
+	}
+
+	if result > limit {
+		return 0, fmt.Errorf("value %d does not fit in %d bits", result, bits)
+	}
+	return result, nil
+}
+
+func registryBinary(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		cleaned := strings.NewReplacer(",", "", " ", "", "\\", "", "\n", "", "\r", "", "\t", "").Replace(v)
+		data, err := hex.DecodeString(cleaned)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string %q", v)
+		}
+		return data, nil
+	case []interface{}:
+		data := make([]byte, 0, len(v))
+		for i, item := range v {
+			b, err := registryInteger(item, 8)
+			if err != nil {
+				return nil, fmt.Errorf("byte %d: %w", i, err)
+			}
+			data = append(data, byte(b))
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("expected a hex string or list of bytes, got %T", value)
+	}
+}
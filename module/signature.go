@@ -0,0 +1,109 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"cat2/liftoff/types"
+)
+
+func (d *DownloadManager) verifySignature(path string, file types.DownloadFile, config types.DownloadConfig) error {
+	keys := config.TrustedKeys
+	var key *types.TrustedKey
+	for i := range keys {
+		if keys[i].Name == file.Signature.Key {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("trusted key %q is not defined", file.Signature.Key)
+	}
+
+	if err := d.validateURL(file.Signature.URL); err != nil {
+		return fmt.Errorf("invalid signature URL %s: %w", file.Signature.URL, err)
+	}
+
+	d.log.Info(fmt.Sprintf("Verifying %s signature with key %s", key.Type, key.Name))
+
+	headers, err := requestHeaders(file.Signature.URL, file, config)
+	if err != nil {
+		return fmt.Errorf("failed to resolve credentials for signature: %w", err)
+	}
+
+	sigData, err := d.downloadWithRetry(file.Signature.URL, headers)
+	if err != nil {
+		return fmt.Errorf("failed to download signature: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "liftoff-sig-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	sigPath := filepath.Join(workDir, "signature")
+	if err := os.WriteFile(sigPath, sigData, 0600); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	keyPath, err := trustedKeyFile(*key, workDir)
+	if err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	switch strings.ToLower(key.Type) {
+	case "minisign":
+		cmd = exec.Command("minisign", "-V", "-q", "-p", keyPath, "-x", sigPath, "-m", path)
+	case "cosign":
+		cmd = exec.Command("cosign", "verify-blob", "--key", keyPath, "--signature", sigPath, path)
+	case "gpg", "pgp":
+		keyring := filepath.Join(workDir, "trusted.gpg")
+		importCmd := exec.Command("gpg", "--batch", "--no-default-keyring", "--keyring", keyring, "--import", keyPath)
+		if output, err := importCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to import GPG key %s: %s", key.Name, strings.TrimSpace(string(output)))
+		}
+		cmd = exec.Command("gpg", "--batch", "--no-default-keyring", "--keyring", keyring,
+			"--trust-model", "always", "--verify", sigPath, path)
+	default:
+		return fmt.Errorf("unsupported signature type: %s", key.Type)
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		d.log.Error(fmt.Sprintf("Signature check failed for %s", file.URL))
+		return fmt.Errorf("%s verification failed: %s", key.Type, strings.TrimSpace(string(output)))
+	}
+
+	d.log.Success(fmt.Sprintf("Signature verified with key %s", key.Name))
+	return nil
+}
+
+func trustedKeyFile(key types.TrustedKey, dir string) (string, error) {
+	if key.Path != "" {
+		expanded := os.ExpandEnv(key.Path)
+		if _, err := os.Stat(expanded); err != nil {
+			return "", fmt.Errorf("trusted key file %s: %w", expanded, err)
+		}
+		return expanded, nil
+	}
+
+	if strings.TrimSpace(key.Key) == "" {
+		return "", fmt.Errorf("trusted key %s has neither key nor path", key.Name)
+	}
+
+	content := key.Key
+	if strings.ToLower(key.Type) == "minisign" && !strings.Contains(content, "\n") {
+		
+		content = "untrusted comment: liftoff trusted key " + key.Name + "\n" + content
+	}
+
+	keyPath := filepath.Join(dir, "trusted.key")
+	if err := os.WriteFile(keyPath, []byte(content+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write trusted key: %w", err)
+	}
+	return keyPath, nil
+}
@@ -0,0 +1,106 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"cat2/liftoff/types"
+	"cat2/liftoff/util"
+)
+
+const (
+	managedBlockBegin = "# BEGIN liftoff"
+	managedBlockEnd   = "# END liftoff"
+)
+
+func sshDir() string {
+	return filepath.Join(os.ExpandEnv("${USERPROFILE}"), ".ssh")
+}
+
+func (g *GitManager) configureSSH(config types.SSHConfig) error {
+	pinned := util.PinnedHostKeys(g.trustedHosts)
+
+	if len(pinned) == 0 && !config.GenerateKey && len(config.Hosts) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(sshDir(), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", sshDir(), err)
+	}
+
+	if len(pinned) > 0 {
+		g.knownHosts = filepath.Join(sshDir(), "liftoff_known_hosts")
+		if err := util.WriteKnownHosts(g.knownHosts, pinned); err != nil {
+			return fmt.Errorf("failed to write pinned host keys: %w", err)
+		}
+		g.log.Success(fmt.Sprintf("Pinned %d SSH host keys in %s", len(pinned), g.knownHosts))
+	}
+
+	g.sshCommand = config.Command
+	if g.sshCommand == "" {
+		g.sshCommand = "ssh"
+	}
+
+	if config.GenerateKey {
+		if err := g.generateKey(config); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Hosts) > 0 {
+		if err := g.writeSSHConfig(config.Hosts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *GitManager) generateKey(config types.SSHConfig) error {
+	keyPath := os.ExpandEnv(config.KeyPath)
+	if keyPath == "" {
+		keyPath = filepath.Join(sshDir(), "id_ed25519")
+	}
+
+	if _, err := os.Stat(keyPath); err == nil {
+		g.log.Info(fmt.Sprintf("SSH key %s already exists", keyPath))
+		return nil
+	}
+
+	comment := config.KeyComment
+	if comment == "" {
+		hostname, _ := os.Hostname()
+		comment = fmt.Sprintf("%s@%s", os.Getenv("USERNAME"), hostname)
+	}
+
+	g.log.Info(fmt.Sprintf("Generating ed25519 key at %s", keyPath))
+
+	cmd := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", comment, "-f", keyPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		g.log.Error("Failed to generate SSH key")
+		return fmt.Errorf("ssh-keygen failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	g.log.Success(fmt.Sprintf("Generated SSH key, public key at %s.pub", keyPath))
+	return nil
+}
+
+func (g *GitManager) writeSSHConfig(hosts []types.SSHHostBlock) error {
+	configPath := filepath.Join(sshDir(), "config")
+
+	block := util.SSHConfigBlock(hosts, g.knownHosts, managedBlockBegin, managedBlockEnd)
+	if err := util.UpdateSSHConfig(configPath, block, managedBlockBegin, managedBlockEnd); err != nil {
+		g.log.Error("Failed to write SSH config")
+		return err
+	}
+
+	g.log.Success(fmt.Sprintf("Wrote %d host blocks to %s", len(hosts), configPath))
+	return nil
+}
+
+func (g *GitManager) gitEnv() []string {
+	return util.GitEnv(g.sshCommand, g.knownHosts)
+}
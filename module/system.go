@@ -68,18 +68,9 @@ func (s *SystemConfigurator) SetRegistryValue(config types.RegistryConfig) error
 	s.log.Info(fmt.Sprintf("Setting registry value: %s\\%s", config.Path, config.Name))
 
 	
-	var root registry.Key
-	switch strings.ToUpper(config.Root) {
-	case "HKEY_LOCAL_MACHINE", "HKLM":
-		root = registry.LOCAL_MACHINE
-	case "HKEY_CURRENT_USER", "HKCU":
-		root = registry.CURRENT_USER
-	case "HKEY_USERS", "HKU":
-		root = registry.USERS
-	case "HKEY_CLASSES_ROOT", "HKCR":
-		root = registry.CLASSES_ROOT
-	default:
-		return fmt.Errorf("invalid registry root: %s", config.Root)
+	root, err := registryRoot(config.Root)
+	if err != nil {
+		return err
 	}
 
 	
@@ -94,31 +85,48 @@ func (s *SystemConfigurator) SetRegistryValue(config types.RegistryConfig) error
 	}
 	defer key.Close()
 
+	entry := registryEntryName(config)
+	name := registryValueName(config.Name)
+
 	
 	switch strings.ToLower(config.Type) {
-	case "string", "sz":
-		err = key.SetStringValue(config.Name, config.Value.(string))
-	case "dword":
-		
-		val, ok := config.Value.(int64)
-		if !ok {
-			return fmt.Errorf("invalid DWORD value for %s", config.Name)
+	case "string", "sz", "reg_sz":
+		var val string
+		if val, err = registryString(config.Value); err == nil {
+			err = key.SetStringValue(name, val)
 		}
-		err = key.SetDWordValue(config.Name, uint32(val))
-	case "binary":
-		
-		val, ok := config.Value.([]byte)
-		if !ok {
-			return fmt.Errorf("invalid binary value for %s", config.Name)
+	case "expand_sz", "reg_expand_sz":
+		var val string
+		if val, err = registryString(config.Value); err == nil {
+			err = key.SetExpandStringValue(name, val)
+		}
+	case "multi_sz", "reg_multi_sz":
+		var val []string
+		if val, err = registryStrings(config.Value); err == nil {
+			err = key.SetStringsValue(name, val)
+		}
+	case "dword", "reg_dword":
+		var val uint64
+		if val, err = registryInteger(config.Value, 32); err == nil {
+			err = key.SetDWordValue(name, uint32(val))
+		}
+	case "qword", "reg_qword":
+		var val uint64
+		if val, err = registryInteger(config.Value, 64); err == nil {
+			err = key.SetQWordValue(name, val)
+		}
+	case "binary", "reg_binary":
+		var val []byte
+		if val, err = registryBinary(config.Value); err == nil {
+			err = key.SetBinaryValue(name, val)
 		}
-		err = key.SetBinaryValue(config.Name, val)
 	default:
-		return fmt.Errorf("unsupported registry value type: %s", config.Type)
+		return fmt.Errorf("%s: unsupported registry value type: %s", entry, config.Type)
 	}
 
 	if err != nil {
-		s.log.Error(fmt.Sprintf("Failed to set registry value: %s", config.Name))
-		return fmt.Errorf("failed to set registry value: %w", err)
+		s.log.Error(fmt.Sprintf("Failed to set registry value: %s", entry))
+		return fmt.Errorf("%s: failed to set registry value: %w", entry, err)
 	}
 
 	s.log.Success(fmt.Sprintf("Set registry value: %s\\%s", config.Path, config.Name))
@@ -126,6 +134,61 @@ func (s *SystemConfigurator) SetRegistryValue(config types.RegistryConfig) error
 }
 
 
+func (s *SystemConfigurator) DeleteRegistryEntry(config types.RegistryConfig) error {
+	root, err := registryRoot(config.Root)
+	if err != nil {
+		return err
+	}
+	entry := registryEntryName(config)
+
+	path, err := registryKeyPath(config.Path)
+	if err != nil {
+		s.log.Error(fmt.Sprintf("Invalid registry entry: %s", entry))
+		return fmt.Errorf("%s: %w", entry, err)
+	}
+
+	if config.Name == "" {
+		s.log.Info(fmt.Sprintf("Deleting registry key: %s", entry))
+		if err := deleteRegistryTree(root, path); err != nil && err != registry.ErrNotExist {
+			s.log.Error(fmt.Sprintf("Failed to delete registry key: %s", entry))
+			return fmt.Errorf("%s: failed to delete registry key: %w", entry, err)
+		}
+		s.log.Success(fmt.Sprintf("Deleted registry key: %s", entry))
+		return nil
+	}
+
+	s.log.Info(fmt.Sprintf("Deleting registry value: %s", entry))
+	key, err := registry.OpenKey(root, path, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to open registry key: %w", entry, err)
+	}
+	defer key.Close()
+
+	if err := key.DeleteValue(registryValueName(config.Name)); err != nil && err != registry.ErrNotExist {
+		s.log.Error(fmt.Sprintf("Failed to delete registry value: %s", entry))
+		return fmt.Errorf("%s: failed to delete registry value: %w", entry, err)
+	}
+
+	s.log.Success(fmt.Sprintf("Deleted registry value: %s", entry))
+	return nil
+}
+
+
+func (s *SystemConfigurator) ApplyRegistry(config types.RegistryConfig) error {
+	switch strings.ToLower(config.State) {
+	case "", "present":
+		return s.SetRegistryValue(config)
+	case "absent":
+		return s.DeleteRegistryEntry(config)
+	default:
+		return fmt.Errorf("%s: invalid registry state: %s", registryEntryName(config), config.State)
+	}
+}
+
+
 func (s *SystemConfigurator) SetDarkMode(enable bool) error {
 	
 	const personalizePath = `Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`
@@ -163,8 +226,14 @@ func (s *SystemConfigurator) Configure(config types.SystemConfig) error {
 	}
 
 	
+	for _, file := range config.RegistryFiles {
+		if err := s.ImportRegFile(file); err != nil {
+			return err
+		}
+	}
+
 	for _, reg := range config.Registry {
-		if err := s.SetRegistryValue(reg); err != nil {
+		if err := s.ApplyRegistry(reg); err != nil {
 			return err
 		}
 	}
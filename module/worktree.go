@@ -0,0 +1,126 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cat2/liftoff/types"
+)
+
+type worktreeInfo struct {
+	path   string
+	branch string
+}
+
+func (g *GitManager) listWorktrees(repoPath string) ([]worktreeInfo, error) {
+	output, err := g.runGit("", "-C", repoPath, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	var worktrees []worktreeInfo
+	var current *worktreeInfo
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			worktrees = append(worktrees, worktreeInfo{path: strings.TrimPrefix(line, "worktree ")})
+			current = &worktrees[len(worktrees)-1]
+		case strings.HasPrefix(line, "branch ") && current != nil:
+			current.branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
+		}
+	}
+	return worktrees, nil
+}
+
+func (g *GitManager) syncWorktrees(repoPath, remote string, config types.Repository) error {
+	if len(config.Worktrees) == 0 {
+		return nil
+	}
+
+	existing, err := g.listWorktrees(repoPath)
+	if err != nil {
+		return err
+	}
+
+	configured := make(map[string]bool)
+	for _, wt := range config.Worktrees {
+		wtPath := os.ExpandEnv(wt.Path)
+		if !filepath.IsAbs(wtPath) {
+			wtPath = filepath.Join(filepath.Dir(repoPath), wtPath)
+		}
+		configured[strings.ToLower(filepath.Clean(wtPath))] = true
+
+		var found *worktreeInfo
+		for i := range existing {
+			if samePath(existing[i].path, wtPath) {
+				found = &existing[i]
+				break
+			}
+		}
+
+		if found == nil {
+			if err := g.addWorktree(repoPath, remote, wtPath, wt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if found.branch != wt.Branch {
+			g.log.Warn(fmt.Sprintf("Worktree %s is on %s, expected %s", wtPath, found.branch, wt.Branch))
+			continue
+		}
+
+		if _, err := g.runGit("", "-C", wtPath, "merge", "--ff-only", "@{upstream}"); err != nil {
+			g.log.Warn(fmt.Sprintf("Could not fast-forward worktree %s: %v", wtPath, err))
+			continue
+		}
+		g.log.Info(fmt.Sprintf("Worktree %s is up to date with %s", wtPath, wt.Branch))
+	}
+
+	for _, wt := range existing {
+		if samePath(wt.path, repoPath) || configured[strings.ToLower(filepath.Clean(filepath.FromSlash(wt.path)))] {
+			continue
+		}
+		g.log.Warn(fmt.Sprintf("Worktree %s (%s) is not in the configuration", filepath.FromSlash(wt.path), wt.branch))
+	}
+
+	return nil
+}
+
+func (g *GitManager) addWorktree(repoPath, remote, wtPath string, wt types.Worktree) error {
+	if wt.Branch == "" {
+		return fmt.Errorf("worktree %s has no branch", wtPath)
+	}
+
+	g.log.Info(fmt.Sprintf("Adding worktree %s for %s", wtPath, wt.Branch))
+
+	args := []string{"-C", repoPath, "worktree", "add"}
+	if wt.New {
+		base := wt.Base
+		if base == "" {
+			base = "HEAD"
+		}
+		args = append(args, "-b", wt.Branch, wtPath, base)
+	} else {
+		
+		if _, err := g.runGit(remote, "-C", repoPath, "fetch", "origin", "+refs/heads/"+wt.Branch+":refs/remotes/origin/"+wt.Branch); err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", wt.Branch, err)
+		}
+		if _, err := g.runGit("", "-C", repoPath, "rev-parse", "--verify", "refs/heads/"+wt.Branch); err != nil {
+			args = append(args, "--track", "-b", wt.Branch, wtPath, "origin/"+wt.Branch)
+		} else {
+			args = append(args, wtPath, wt.Branch)
+		}
+	}
+
+	if _, err := g.runGit("", args...); err != nil {
+		g.log.Error(fmt.Sprintf("Failed to add worktree %s", wtPath))
+		return fmt.Errorf("git worktree add failed: %w", err)
+	}
+
+	g.log.Success(fmt.Sprintf("Added worktree %s", wtPath))
+	return nil
+}
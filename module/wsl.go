@@ -1,16 +1,41 @@
 package module
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/url"
+	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"cat2/liftoff/types"
 	"cat2/liftoff/util"
 )
 
+const (
+	wslConfPath  = "/etc/wsl.conf"
+	wslStateName = "wsl"
+)
+
+var (
+	wslUserName = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+	wslSize     = regexp.MustCompile(`(?i)^\d+(\.\d+)?\s*(B|KB|MB|GB|TB)?$`)
+)
+
+
+type wslState struct {
+	Provisioned map[string][]string `json:"provisioned"`
+}
+
 type WSLManager struct {
-	log *util.Logger
+	log       *util.Logger
+	downloads *DownloadManager
+	policy    types.DownloadConfig
 }
 
 func NewWSLManager(log *util.Logger) *WSLManager {
@@ -19,6 +44,12 @@ func NewWSLManager(log *util.Logger) *WSLManager {
 	}
 }
 
+
+func (w *WSLManager) UseDownloads(downloads *DownloadManager, config types.DownloadConfig) {
+	w.downloads = downloads
+	w.policy = config
+}
+
 func (w *WSLManager) Configure(config types.WSLConfig) error {
 	
 	if !w.isWSLAvailable() {
@@ -27,10 +58,18 @@ func (w *WSLManager) Configure(config types.WSLConfig) error {
 	}
 
 	
+	if err := w.configureGlobal(config.Global); err != nil {
+		return err
+	}
+
+	
 	for _, dist := range config.Distributions {
 		if err := w.installDistribution(dist); err != nil {
 			return err
 		}
+		if err := w.configureDistribution(dist); err != nil {
+			return err
+		}
 	}
 
 	
@@ -48,16 +87,27 @@ func (w *WSLManager) isWSLAvailable() bool {
 	return cmd.Run() == nil
 }
 
+
+func wslCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("wsl", args...)
+	cmd.Env = append(os.Environ(), "WSL_UTF8=1")
+	return cmd
+}
+
+func wslText(output []byte) string {
+	return strings.ReplaceAll(string(output), "\x00", "")
+}
+
 func (w *WSLManager) isDistributionInstalled(name string) bool {
-	cmd := exec.Command("wsl", "-l", "-q")
+	cmd := wslCommand("-l", "-q")
 	output, err := cmd.Output()
 	if err != nil {
 		return false
 	}
 
-	installedDistros := strings.Split(strings.TrimSpace(string(output)), "\n")
+	installedDistros := strings.Split(strings.TrimSpace(wslText(output)), "\n")
 	for _, distro := range installedDistros {
-		if strings.TrimSpace(distro) == name {
+		if strings.EqualFold(strings.TrimSpace(distro), name) {
 			return true
 		}
 	}
@@ -65,32 +115,169 @@ func (w *WSLManager) isDistributionInstalled(name string) bool {
 }
 
 func (w *WSLManager) installDistribution(dist types.WSLDistribution) error {
+	version, err := wslVersion(dist)
+	if err != nil {
+		w.log.Error(err.Error())
+		return err
+	}
+
 	if w.isDistributionInstalled(dist.Name) {
 		w.log.Info(fmt.Sprintf("Distribution %s is already installed", dist.Name))
-		return nil
+	} else if dist.Source != "" {
+		if err := w.importDistribution(dist, version); err != nil {
+			return err
+		}
+	} else {
+		w.log.Info(fmt.Sprintf("Installing WSL distribution: %s", dist.Name))
+
+		cmd := wslCommand("--install", "-d", dist.Name)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			w.log.Error(fmt.Sprintf("Failed to install %s: %s", dist.Name, wslText(output)))
+			return fmt.Errorf("failed to install %s: %w", dist.Name, err)
+		}
+
+		w.log.Success(fmt.Sprintf("Successfully installed %s", dist.Name))
 	}
 
-	w.log.Info(fmt.Sprintf("Installing WSL distribution: %s", dist.Name))
+	if version != "" {
+		return w.setVersion(dist.Name, version)
+	}
+	return nil
+}
 
-	var cmd *exec.Cmd
-	if dist.Version == "latest" || dist.Version == "" {
-		cmd = exec.Command("wsl", "--install", "-d", dist.Name)
-	} else {
-		
-		
-		w.log.Warn(fmt.Sprintf("Version specification is not supported. Installing latest version of %s", dist.Name))
-		cmd = exec.Command("wsl", "--install", "-d", dist.Name)
+
+func wslVersion(dist types.WSLDistribution) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(dist.Version)) {
+	case "", "latest":
+		return "", nil
+	case "1", "wsl1":
+		return "1", nil
+	case "2", "wsl2":
+		return "2", nil
+	default:
+		return "", fmt.Errorf("invalid WSL version %q for %s, expected 1 or 2", dist.Version, dist.Name)
+	}
+}
+
+
+func (w *WSLManager) installedVersions() (map[string]string, error) {
+	output, err := wslCommand("-l", "-v").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string)
+	for _, line := range strings.Split(wslText(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "*" {
+			fields = fields[1:]
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		last := fields[len(fields)-1]
+		if last == "1" || last == "2" {
+			versions[strings.ToLower(fields[0])] = last
+		}
+	}
+	return versions, nil
+}
+
+func (w *WSLManager) setVersion(name, version string) error {
+	versions, err := w.installedVersions()
+	if err != nil {
+		w.log.Error("Failed to list WSL distributions")
+		return fmt.Errorf("failed to list WSL distributions: %w", err)
+	}
+
+	current, ok := versions[strings.ToLower(name)]
+	if !ok || current == version {
+		return nil
 	}
 
+	w.log.Info(fmt.Sprintf("Converting %s from WSL %s to WSL %s", name, current, version))
+
+	cmd := wslCommand("--set-version", name, version)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		w.log.Error(fmt.Sprintf("Failed to install %s: %s", dist.Name, string(output)))
-		return fmt.Errorf("failed to install %s: %w", dist.Name, err)
+		w.log.Error(fmt.Sprintf("Failed to set WSL version for %s: %s", name, wslText(output)))
+		return fmt.Errorf("failed to set WSL version for %s: %w", name, err)
 	}
 
-	w.log.Success(fmt.Sprintf("Successfully installed %s", dist.Name))
+	w.log.Success(fmt.Sprintf("%s now runs on WSL %s", name, version))
 	return nil
 }
 
+
+func (w *WSLManager) importDistribution(dist types.WSLDistribution, version string) error {
+	source, err := w.fetchSource(dist)
+	if err != nil {
+		return err
+	}
+
+	location := dist.Location
+	if location == "" {
+		location = filepath.Join(os.ExpandEnv("${USERPROFILE}"), "Liftoff", "WSL", dist.Name)
+	}
+	if err := os.MkdirAll(location, 0755); err != nil {
+		w.log.Error(fmt.Sprintf("Failed to create %s", location))
+		return fmt.Errorf("failed to create install location %s: %w", location, err)
+	}
+
+	args := []string{"--import", dist.Name, location, source}
+	if strings.EqualFold(filepath.Ext(source), ".vhdx") {
+		args = append(args, "--vhd")
+	}
+	if version != "" {
+		args = append(args, "--version", version)
+	}
+
+	w.log.Info(fmt.Sprintf("Importing WSL distribution %s from %s into %s", dist.Name, source, location))
+
+	if output, err := wslCommand(args...).CombinedOutput(); err != nil {
+		w.log.Error(fmt.Sprintf("Failed to import %s: %s", dist.Name, wslText(output)))
+		return fmt.Errorf("failed to import %s: %w", dist.Name, err)
+	}
+
+	w.log.Success(fmt.Sprintf("Successfully imported %s", dist.Name))
+	return nil
+}
+
+
+func (w *WSLManager) fetchSource(dist types.WSLDistribution) (string, error) {
+	if !strings.HasPrefix(dist.Source, "http://") && !strings.HasPrefix(dist.Source, "https://") {
+		if _, err := os.Stat(dist.Source); err != nil {
+			w.log.Error(fmt.Sprintf("Source for %s not found: %s", dist.Name, dist.Source))
+			return "", fmt.Errorf("source for %s: %w", dist.Name, err)
+		}
+		return dist.Source, nil
+	}
+
+	if w.downloads == nil {
+		return "", fmt.Errorf("cannot download %s: downloads are not configured", dist.Source)
+	}
+
+	parsed, err := url.Parse(dist.Source)
+	if err != nil {
+		return "", fmt.Errorf("invalid source URL %s: %w", dist.Source, err)
+	}
+	dest := filepath.Join(os.ExpandEnv("${USERPROFILE}"), "Liftoff", "Downloads", "WSL", path.Base(parsed.Path))
+
+	if len(dist.SourceURLs) == 0 {
+		dist.SourceURLs = []string{dist.Source}
+	}
+	file := types.DownloadFile{
+		URL:    dist.Source,
+		URLs:   dist.SourceURLs,
+		Dest:   dest,
+		SHA256: dist.SHA256,
+	}
+	if err := w.downloads.downloadFile(file, w.policy); err != nil {
+		w.log.Error(fmt.Sprintf("Failed to download image for %s", dist.Name))
+		return "", fmt.Errorf("failed to download image for %s: %w", dist.Name, err)
+	}
+	return dest, nil
+}
+
 func (w *WSLManager) setDefaultDistribution(name string) error {
 	if !w.isDistributionInstalled(name) {
 		w.log.Error(fmt.Sprintf("Distribution %s is not installed", name))
@@ -108,3 +295,277 @@ func (w *WSLManager) setDefaultDistribution(name string) error {
 	w.log.Success(fmt.Sprintf("Successfully set %s as default distribution", name))
 	return nil
 }
+
+
+func (w *WSLManager) runAsRoot(dist, script string) (string, error) {
+	cmd := wslCommand("-d", dist, "-u", "root", "--", "sh", "-s")
+	cmd.Stdin = strings.NewReader(strings.ReplaceAll(script, "\r\n", "\n"))
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(wslText(output)), err
+}
+
+func (w *WSLManager) configureDistribution(dist types.WSLDistribution) error {
+	if dist.DefaultUser == "" && len(dist.WSLConf) == 0 && len(dist.Provision) == 0 {
+		return nil
+	}
+
+	conf := dist.WSLConf
+	if dist.DefaultUser != "" {
+		if err := w.ensureUser(dist.Name, dist.DefaultUser, dist.DefaultUserSudo); err != nil {
+			return err
+		}
+
+		conf = make(map[string]map[string]interface{}, len(dist.WSLConf)+1)
+		for section, values := range dist.WSLConf {
+			conf[section] = values
+		}
+		user := make(map[string]interface{}, len(conf["user"])+1)
+		for key, value := range conf["user"] {
+			user[key] = value
+		}
+		user["default"] = dist.DefaultUser
+		conf["user"] = user
+	}
+
+	if len(conf) > 0 {
+		if err := w.writeWSLConf(dist.Name, conf); err != nil {
+			return err
+		}
+	}
+
+	if len(dist.Provision) > 0 {
+		if err := w.provision(dist); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *WSLManager) ensureUser(dist, user string, sudo bool) error {
+	if !wslUserName.MatchString(user) {
+		return fmt.Errorf("invalid default user %q for %s", user, dist)
+	}
+
+	
+	script := fmt.Sprintf(`if ! id -u %[1]s >/dev/null 2>&1; then
+  useradd -m -s /bin/bash %[1]s || exit 1
+  passwd -l %[1]s >/dev/null
+  echo created
+fi
+`, user)
+
+	sudoers := "/etc/sudoers.d/liftoff-" + user
+	if sudo {
+		script += fmt.Sprintf(`if [ ! -f %[2]s ]; then
+  mkdir -p /etc/sudoers.d
+  echo '%[1]s ALL=(ALL) NOPASSWD:ALL' > %[2]s.tmp && chmod 0440 %[2]s.tmp && mv %[2]s.tmp %[2]s || exit 1
+  echo sudo-granted
+fi
+`, user, sudoers)
+	} else {
+		script += fmt.Sprintf(`if [ -f %[1]s ]; then
+  rm -f %[1]s || exit 1
+  echo sudo-revoked
+fi
+`, sudoers)
+	}
+
+	output, err := w.runAsRoot(dist, script)
+	if err != nil {
+		w.log.Error(fmt.Sprintf("Failed to configure user %s in %s: %s", user, dist, output))
+		return fmt.Errorf("failed to configure user %s in %s: %w", user, dist, err)
+	}
+	if strings.Contains(output, "created") {
+		w.log.Success(fmt.Sprintf("Created user %s in %s", user, dist))
+	}
+	if strings.Contains(output, "sudo-granted") {
+		w.log.Success(fmt.Sprintf("Granted sudo to %s in %s", user, dist))
+	}
+	if strings.Contains(output, "sudo-revoked") {
+		w.log.Success(fmt.Sprintf("Revoked sudo from %s in %s", user, dist))
+	}
+	return nil
+}
+
+func (w *WSLManager) writeWSLConf(dist string, conf map[string]map[string]interface{}) error {
+	current, err := w.runAsRoot(dist, "cat "+wslConfPath+" 2>/dev/null || true")
+	if err != nil {
+		w.log.Error(fmt.Sprintf("Failed to read %s in %s", wslConfPath, dist))
+		return fmt.Errorf("failed to read %s in %s: %w", wslConfPath, dist, err)
+	}
+
+	content, changes := util.MergeINISections(current, conf)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	w.log.Info(fmt.Sprintf("Writing %s in %s", wslConfPath, dist))
+	for _, change := range changes {
+		w.log.Info(fmt.Sprintf("%s: %q -> %q", change.Key, change.Old, change.New))
+	}
+
+	script := fmt.Sprintf("cat > %s <<'LIFTOFF_EOF'\n%sLIFTOFF_EOF\n", wslConfPath, content)
+	if output, err := w.runAsRoot(dist, script); err != nil {
+		w.log.Error(fmt.Sprintf("Failed to write %s in %s: %s", wslConfPath, dist, output))
+		return fmt.Errorf("failed to write %s in %s: %w", wslConfPath, dist, err)
+	}
+
+	
+	if output, err := wslCommand("--terminate", dist).CombinedOutput(); err != nil {
+		w.log.Error(fmt.Sprintf("Failed to restart %s: %s", dist, wslText(output)))
+		return fmt.Errorf("failed to restart %s: %w", dist, err)
+	}
+
+	w.log.Success(fmt.Sprintf("Updated %s in %s", wslConfPath, dist))
+	return nil
+}
+
+
+func provisionScript(entry string) (string, error) {
+	path := os.ExpandEnv(entry)
+	if !strings.ContainsAny(path, "\n") && strings.HasSuffix(strings.ToLower(path), ".sh") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read provisioning script %s: %w", path, err)
+		}
+		return string(data), nil
+	}
+	return entry, nil
+}
+
+func (w *WSLManager) provision(dist types.WSLDistribution) error {
+	var state wslState
+	if err := util.LoadState(wslStateName, &state); err != nil {
+		w.log.Error("Failed to load WSL state")
+		return err
+	}
+	if state.Provisioned == nil {
+		state.Provisioned = make(map[string][]string)
+	}
+
+	done := make(map[string]bool)
+	for _, hash := range state.Provisioned[dist.Name] {
+		done[hash] = true
+	}
+
+	for i, entry := range dist.Provision {
+		script, err := provisionScript(entry)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256([]byte(strings.ReplaceAll(script, "\r\n", "\n")))
+		hash := hex.EncodeToString(sum[:])
+		if done[hash] {
+			continue
+		}
+
+		w.log.Info(fmt.Sprintf("Running provisioning script %d/%d in %s", i+1, len(dist.Provision), dist.Name))
+		output, err := w.runAsRoot(dist.Name, script)
+		if err != nil {
+			w.log.Error(fmt.Sprintf("Provisioning script %d failed in %s", i+1, dist.Name))
+			w.log.Error(output)
+			return fmt.Errorf("provisioning script %d failed in %s: %w", i+1, dist.Name, err)
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if line != "" {
+				w.log.Debug(strings.TrimRight(line, "\r"))
+			}
+		}
+
+		done[hash] = true
+		state.Provisioned[dist.Name] = append(state.Provisioned[dist.Name], hash)
+		if err := util.SaveState(wslStateName, state); err != nil {
+			w.log.Error("Failed to save WSL state")
+			return err
+		}
+		w.log.Success(fmt.Sprintf("Provisioning script %d finished in %s", i+1, dist.Name))
+	}
+
+	return nil
+}
+
+
+func wslGlobalSettings(config types.WSLGlobalConfig) (map[string]string, error) {
+	settings := make(map[string]string)
+
+	if config.Memory != "" {
+		if !wslSize.MatchString(config.Memory) {
+			return nil, fmt.Errorf("invalid WSL memory size %q", config.Memory)
+		}
+		settings["memory"] = config.Memory
+	}
+	if config.Swap != "" {
+		if !wslSize.MatchString(config.Swap) {
+			return nil, fmt.Errorf("invalid WSL swap size %q", config.Swap)
+		}
+		settings["swap"] = config.Swap
+	}
+	if config.Processors < 0 {
+		return nil, fmt.Errorf("invalid WSL processor count %d", config.Processors)
+	}
+	if config.Processors > 0 {
+		settings["processors"] = strconv.Itoa(config.Processors)
+	}
+	if config.NetworkingMode != "" {
+		mode := strings.ToLower(config.NetworkingMode)
+		switch mode {
+		case "nat", "mirrored", "virtioproxy", "none":
+		default:
+			return nil, fmt.Errorf("invalid WSL networking mode %q", config.NetworkingMode)
+		}
+		settings["networkingMode"] = mode
+	}
+	if config.DNSTunneling != nil {
+		settings["dnsTunneling"] = util.FormatINIValue(*config.DNSTunneling)
+	}
+	if config.AutoProxy != nil {
+		settings["autoProxy"] = util.FormatINIValue(*config.AutoProxy)
+	}
+
+	return settings, nil
+}
+
+
+func (w *WSLManager) configureGlobal(config types.WSLGlobalConfig) error {
+	settings, err := wslGlobalSettings(config)
+	if err != nil {
+		w.log.Error("Invalid WSL global settings")
+		return err
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(os.ExpandEnv("${USERPROFILE}"), ".wslconfig")
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		w.log.Error(fmt.Sprintf("Failed to read %s", path))
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	merged, changes := util.MergeINI(string(content), "wsl2", settings)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	w.log.Info(fmt.Sprintf("Updating %s", path))
+	for _, change := range changes {
+		w.log.Info(fmt.Sprintf("%s: %q -> %q", change.Key, change.Old, change.New))
+	}
+
+	if err := writeFileAtomic(path, []byte(merged)); err != nil {
+		w.log.Error(fmt.Sprintf("Failed to write %s", path))
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	
+	if output, err := wslCommand("--shutdown").CombinedOutput(); err != nil {
+		w.log.Error(fmt.Sprintf("Failed to shut down WSL: %s", wslText(output)))
+		return fmt.Errorf("failed to shut down WSL: %w", err)
+	}
+
+	w.log.Success("Updated WSL global settings and shut down WSL to apply them")
+	return nil
+}
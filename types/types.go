@@ -1,98 +1,347 @@
 package types
 
+import "gopkg.in/yaml.v3"
+
 
 type Config struct {
-	Packages    PackageConfig     `toml:"packages"`
-	System      SystemConfig      `toml:"system"`
-	Git         GitConfig         `toml:"git"`
-	Environment EnvironmentConfig `toml:"environment"`
-	WSL         WSLConfig         `toml:"wsl"`
-	Downloads   DownloadConfig    `toml:"downloads"`
-	Network     NetworkConfig     `toml:"network"`
-	FileAssoc   FileAssocConfig   `toml:"file_associations"`
+	Packages    PackageConfig     `toml:"packages" yaml:"packages"`
+	System      SystemConfig      `toml:"system" yaml:"system"`
+	Git         GitConfig         `toml:"git" yaml:"git"`
+	Environment EnvironmentConfig `toml:"environment" yaml:"environment"`
+	WSL         WSLConfig         `toml:"wsl" yaml:"wsl"`
+	Downloads   DownloadConfig    `toml:"downloads" yaml:"downloads"`
+	Network     NetworkConfig     `toml:"network" yaml:"network"`
+	FileAssoc   FileAssocConfig   `toml:"file_associations" yaml:"file_associations"`
+	Mirrors     MirrorConfig      `toml:"mirrors" yaml:"mirrors"`
+}
+
+
+type MirrorConfig struct {
+	Rewrites []URLRewrite `toml:"rewrites" yaml:"rewrites"`
+}
+
+
+type URLRewrite struct {
+	Prefix string `toml:"prefix" yaml:"prefix"`
+	Mirror string `toml:"mirror" yaml:"mirror"`
 }
 
 
 type GitConfig struct {
-	Repositories []Repository `toml:"repositories"`
+	Repositories []Repository              `toml:"repositories" yaml:"repositories"`
+	TrustedHosts []GitHost                 `toml:"trusted_hosts,omitempty" yaml:"trusted_hosts,omitempty"`
+	SSH          SSHConfig                 `toml:"ssh,omitempty" yaml:"ssh,omitempty"`
+	Concurrency  int                       `toml:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	Config       map[string]GitConfigValue `toml:"config,omitempty" yaml:"config,omitempty"`
+	Includes     []GitInclude              `toml:"includes,omitempty" yaml:"includes,omitempty"`
+}
+
+
+type GitConfigValue []string
+
+func (v *GitConfigValue) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var value string
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		*v = GitConfigValue{value}
+		return nil
+	}
+
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+
+type GitInclude struct {
+	GitDir string                    `toml:"gitdir" yaml:"gitdir"`
+	Path   string                    `toml:"path,omitempty" yaml:"path,omitempty"`
+	Config map[string]GitConfigValue `toml:"config" yaml:"config"`
+}
+
+
+type GitHost struct {
+	Host       string   `toml:"host" yaml:"host"`
+	AllowSSH   bool     `toml:"allow_ssh,omitempty" yaml:"allow_ssh,omitempty"`
+	KnownHosts []string `toml:"known_hosts,omitempty" yaml:"known_hosts,omitempty"`
+}
+
+
+type SSHConfig struct {
+	Command     string         `toml:"command,omitempty" yaml:"command,omitempty"`
+	GenerateKey bool           `toml:"generate_key,omitempty" yaml:"generate_key,omitempty"`
+	KeyPath     string         `toml:"key_path,omitempty" yaml:"key_path,omitempty"`
+	KeyComment  string         `toml:"key_comment,omitempty" yaml:"key_comment,omitempty"`
+	Hosts       []SSHHostBlock `toml:"hosts,omitempty" yaml:"hosts,omitempty"`
+}
+
+
+type SSHHostBlock struct {
+	Host         string `toml:"host" yaml:"host"`
+	HostName     string `toml:"hostname,omitempty" yaml:"hostname,omitempty"`
+	User         string `toml:"user,omitempty" yaml:"user,omitempty"`
+	Port         int    `toml:"port,omitempty" yaml:"port,omitempty"`
+	IdentityFile string `toml:"identity_file,omitempty" yaml:"identity_file,omitempty"`
 }
 
 
 type Repository struct {
-	URL           string `toml:"url"`
-	Path          string `toml:"path"`
-	Branch        string `toml:"branch,omitempty"`
-	Depth         int    `toml:"depth,omitempty"`
-	SubmoduleInit bool   `toml:"submodule_init,omitempty"`
+	URL           string     `toml:"url" yaml:"url"`
+	URLs          []string   `toml:"urls,omitempty" yaml:"urls,omitempty"`
+	Path          string     `toml:"path" yaml:"path"`
+	Branch        string     `toml:"branch,omitempty" yaml:"branch,omitempty"`
+	Depth         int        `toml:"depth,omitempty" yaml:"depth,omitempty"`
+	SubmoduleInit bool       `toml:"submodule_init,omitempty" yaml:"submodule_init,omitempty"`
+	Update        string     `toml:"update,omitempty" yaml:"update,omitempty"`
+	Filter        string     `toml:"filter,omitempty" yaml:"filter,omitempty"`
+	Sparse        []string   `toml:"sparse,omitempty" yaml:"sparse,omitempty"`
+	LFS           bool       `toml:"lfs,omitempty" yaml:"lfs,omitempty"`
+	Tag           string     `toml:"tag,omitempty" yaml:"tag,omitempty"`
+	Commit        string     `toml:"commit,omitempty" yaml:"commit,omitempty"`
+	PostClone     []Hook     `toml:"post_clone,omitempty" yaml:"post_clone,omitempty"`
+	Worktrees     []Worktree `toml:"worktrees,omitempty" yaml:"worktrees,omitempty"`
+}
+
+
+type Worktree struct {
+	Path   string `toml:"path" yaml:"path"`
+	Branch string `toml:"branch" yaml:"branch"`
+	New    bool   `toml:"new,omitempty" yaml:"new,omitempty"`
+	Base   string `toml:"base,omitempty" yaml:"base,omitempty"`
+}
+
+
+type Hook struct {
+	Command string `toml:"command" yaml:"command"`
+	Timeout int    `toml:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Always  bool   `toml:"always,omitempty" yaml:"always,omitempty"`
 }
 
 
 type PackageConfig struct {
-	Chocolatey []string `toml:"chocolatey"`
-	Winget     []string `toml:"winget"`
+	Chocolatey []string `toml:"chocolatey" yaml:"chocolatey"`
+	Winget     []string `toml:"winget" yaml:"winget"`
 }
 
 
 type SystemConfig struct {
-	DarkMode bool              `toml:"dark_mode"`
-	Folders  []string          `toml:"folders"`
-	Files    map[string]string `toml:"files"`
-	Registry []RegistryConfig  `toml:"registry"`
+	DarkMode      bool              `toml:"dark_mode" yaml:"dark_mode"`
+	Folders       []string          `toml:"folders" yaml:"folders"`
+	Files         map[string]string `toml:"files" yaml:"files"`
+	Registry      []RegistryConfig  `toml:"registry" yaml:"registry"`
+	RegistryFiles []string          `toml:"registry_files" yaml:"registry_files"`
 }
 
 
 type RegistryConfig struct {
-	Root  string      `toml:"root"`  
-	Path  string      `toml:"path"`  
-	Name  string      `toml:"name"`  
-	Type  string      `toml:"type"`  
-	Value interface{} `toml:"value"` 
+	Root  string      `toml:"root" yaml:"root"`  
+	Path  string      `toml:"path" yaml:"path"`  
+	Name  string      `toml:"name" yaml:"name"`  
+	Type  string      `toml:"type" yaml:"type"`  
+	Value interface{} `toml:"value" yaml:"value"` 
+	State string      `toml:"state,omitempty" yaml:"state,omitempty"`
 }
 
 type EnvironmentConfig struct {
-	PathAppend []string          `toml:"path_append"`
-	Variables  map[string]string `toml:"variables"`
+	PathAppend       []string               `toml:"path_append" yaml:"path_append"`
+	PathScope        string                 `toml:"path_scope,omitempty" yaml:"path_scope,omitempty"`
+	PathPrepend      []string               `toml:"path_prepend,omitempty" yaml:"path_prepend,omitempty"`
+	PathRemove       []string               `toml:"path_remove,omitempty" yaml:"path_remove,omitempty"`
+	PathPruneMissing bool                   `toml:"path_prune_missing,omitempty" yaml:"path_prune_missing,omitempty"`
+	Variables        map[string]EnvVariable `toml:"variables" yaml:"variables"`
+	Unset            []EnvUnset             `toml:"unset,omitempty" yaml:"unset,omitempty"`
+}
+
+
+type EnvVariable struct {
+	Value string `toml:"value" yaml:"value"`
+	Scope string `toml:"scope,omitempty" yaml:"scope,omitempty"`
+}
+
+
+func (v *EnvVariable) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		v.Scope = ""
+		return node.Decode(&v.Value)
+	}
+
+	type plain EnvVariable
+	return node.Decode((*plain)(v))
+}
+
+
+type EnvUnset struct {
+	Name  string `toml:"name" yaml:"name"`
+	Scope string `toml:"scope,omitempty" yaml:"scope,omitempty"`
+}
+
+func (u *EnvUnset) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		u.Scope = ""
+		return node.Decode(&u.Name)
+	}
+
+	type plain EnvUnset
+	return node.Decode((*plain)(u))
 }
 
 
 type WSLConfig struct {
-	DefaultDistro string            `toml:"default_distro"`
-	Distributions []WSLDistribution `toml:"distributions"`
+	DefaultDistro string            `toml:"default_distro" yaml:"default_distro"`
+	Distributions []WSLDistribution `toml:"distributions" yaml:"distributions"`
+	Global        WSLGlobalConfig   `toml:"global,omitempty" yaml:"global,omitempty"`
+}
+
+
+type WSLGlobalConfig struct {
+	Memory         string `toml:"memory,omitempty" yaml:"memory,omitempty"`
+	Processors     int    `toml:"processors,omitempty" yaml:"processors,omitempty"`
+	Swap           string `toml:"swap,omitempty" yaml:"swap,omitempty"`
+	NetworkingMode string `toml:"networking_mode,omitempty" yaml:"networking_mode,omitempty"`
+	DNSTunneling   *bool  `toml:"dns_tunneling,omitempty" yaml:"dns_tunneling,omitempty"`
+	AutoProxy      *bool  `toml:"auto_proxy,omitempty" yaml:"auto_proxy,omitempty"`
 }
 
 
 type WSLDistribution struct {
-	Name    string `toml:"name"`
-	Version string `toml:"version"`
+	Name            string                            `toml:"name" yaml:"name"`
+	Version         string                            `toml:"version" yaml:"version"`
+	DefaultUser     string                            `toml:"default_user,omitempty" yaml:"default_user,omitempty"`
+	DefaultUserSudo bool                              `toml:"default_user_sudo,omitempty" yaml:"default_user_sudo,omitempty"`
+	WSLConf         map[string]map[string]interface{} `toml:"wsl_conf,omitempty" yaml:"wsl_conf,omitempty"`
+	Provision       []string                          `toml:"provision,omitempty" yaml:"provision,omitempty"`
+	Source          string                            `toml:"source,omitempty" yaml:"source,omitempty"`
+	SourceURLs      []string                          `toml:"source_urls,omitempty" yaml:"source_urls,omitempty"`
+	SHA256          string                            `toml:"sha256,omitempty" yaml:"sha256,omitempty"`
+	Location        string                            `toml:"install_location,omitempty" yaml:"install_location,omitempty"`
 }
 
 type DownloadConfig struct {
-	Files []DownloadFile `toml:"files"`
+	Files            []DownloadFile        `toml:"files" yaml:"files"`
+	Concurrency      int                   `toml:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	PerHostLimit     int                   `toml:"per_host_limit,omitempty" yaml:"per_host_limit,omitempty"`
+	FailFast         bool                  `toml:"fail_fast,omitempty" yaml:"fail_fast,omitempty"`
+	RequireChecksums bool                  `toml:"require_checksums,omitempty" yaml:"require_checksums,omitempty"`
+	TrustedKeys      []TrustedKey          `toml:"trusted_keys,omitempty" yaml:"trusted_keys,omitempty"`
+	HostAuth         map[string]AuthConfig `toml:"host_auth,omitempty" yaml:"host_auth,omitempty"`
 }
 
 type DownloadFile struct {
-	URL    string `toml:"url"`
-	Dest   string `toml:"dest"`
-	SHA256 string `toml:"sha256,omitempty"`
-	Rename string `toml:"rename,omitempty"`
+	URL       string          `toml:"url" yaml:"url"`
+	URLs      []string        `toml:"urls,omitempty" yaml:"urls,omitempty"`
+	Dest      string          `toml:"dest" yaml:"dest"`
+	SHA256    string          `toml:"sha256,omitempty" yaml:"sha256,omitempty"`
+	SHA512    string          `toml:"sha512,omitempty" yaml:"sha512,omitempty"`
+	Signature SignatureConfig `toml:"signature,omitempty" yaml:"signature,omitempty"`
+	Auth      AuthConfig      `toml:"auth,omitempty" yaml:"auth,omitempty"`
+	Rename    string          `toml:"rename,omitempty" yaml:"rename,omitempty"`
+}
+
+
+type AuthConfig struct {
+	Headers     map[string]string `toml:"headers,omitempty" yaml:"headers,omitempty"`
+	BearerToken string            `toml:"bearer_token,omitempty" yaml:"bearer_token,omitempty"`
+	Username    string            `toml:"username,omitempty" yaml:"username,omitempty"`
+	Password    string            `toml:"password,omitempty" yaml:"password,omitempty"`
+}
+
+
+type SignatureConfig struct {
+	URL string `toml:"url" yaml:"url"`
+	Key string `toml:"key" yaml:"key"`
+}
+
+
+type TrustedKey struct {
+	Name string `toml:"name" yaml:"name"`
+	Type string `toml:"type" yaml:"type"`
+	Key  string `toml:"key,omitempty" yaml:"key,omitempty"`
+	Path string `toml:"path,omitempty" yaml:"path,omitempty"`
 }
 
 
 type NetworkConfig struct {
-	DNSServers   []string          `toml:"dns_servers"`
-	HostsEntries map[string]string `toml:"hosts_entries"`
-	Proxy        ProxyConfig       `toml:"proxy,omitempty"`
+	DNSServers   []string             `toml:"dns_servers" yaml:"dns_servers"`
+	DNS          []DNSRule            `toml:"dns,omitempty" yaml:"dns,omitempty"`
+	HostsEntries map[string]HostAddrs `toml:"hosts_entries" yaml:"hosts_entries"`
+	HostsPath    string               `toml:"hosts_path,omitempty" yaml:"hosts_path,omitempty"`
+	Proxy        ProxyConfig          `toml:"proxy,omitempty" yaml:"proxy,omitempty"`
+	Firewall     []FirewallRule       `toml:"firewall,omitempty" yaml:"firewall,omitempty"`
+}
+
+
+type HostAddrs []string
+
+func (a *HostAddrs) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var value string
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		*a = HostAddrs{value}
+		return nil
+	}
+
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*a = values
+	return nil
+}
+
+
+type FirewallRule struct {
+	Name      string   `toml:"name" yaml:"name"`
+	Direction string   `toml:"direction,omitempty" yaml:"direction,omitempty"`
+	Action    string   `toml:"action,omitempty" yaml:"action,omitempty"`
+	Protocol  string   `toml:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Ports     []string `toml:"ports,omitempty" yaml:"ports,omitempty"`
+	Program   string   `toml:"program,omitempty" yaml:"program,omitempty"`
+	Profile   string   `toml:"profile,omitempty" yaml:"profile,omitempty"`
+}
+
+
+type DNSRule struct {
+	Interfaces   []string          `toml:"interfaces,omitempty" yaml:"interfaces,omitempty"`
+	Servers      []string          `toml:"servers,omitempty" yaml:"servers,omitempty"`
+	DHCP         bool              `toml:"dhcp,omitempty" yaml:"dhcp,omitempty"`
+	DoHTemplates map[string]string `toml:"doh_templates,omitempty" yaml:"doh_templates,omitempty"`
 }
 
 type ProxyConfig struct {
-	Enable   bool   `toml:"enable"`
-	Server   string `toml:"server"`
-	Port     int    `toml:"port"`
-	Username string `toml:"username,omitempty"`
-	Password string `toml:"password,omitempty"`
+	Enable   bool     `toml:"enable" yaml:"enable"`
+	Server   string   `toml:"server" yaml:"server"`
+	Port     int      `toml:"port" yaml:"port"`
+	Username string   `toml:"username,omitempty" yaml:"username,omitempty"`
+	Password string   `toml:"password,omitempty" yaml:"password,omitempty"`
+	Bypass   []string `toml:"bypass,omitempty" yaml:"bypass,omitempty"`
+	PACURL   string   `toml:"pac_url,omitempty" yaml:"pac_url,omitempty"`
+	CABundle string   `toml:"ca_bundle,omitempty" yaml:"ca_bundle,omitempty"`
+
+	present bool
+}
+
+
+func (p *ProxyConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain ProxyConfig
+	if err := node.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	p.present = true
+	return nil
+}
+
+func (p ProxyConfig) Present() bool {
+	return p.present
 }
 
 
 type FileAssocConfig struct {
-	Associations map[string]string `toml:"associations"` 
+	Associations map[string]string `toml:"associations" yaml:"associations"` 
 }
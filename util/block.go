@@ -0,0 +1,55 @@
+package util
+
+import "strings"
+
+
+func ReplaceManagedBlock(content, block, begin, end string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var kept, pending []string
+	inBlock := false
+	insertAt := -1
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == begin && !inBlock:
+			inBlock = true
+			pending = nil
+			if insertAt < 0 {
+				insertAt = len(kept)
+			}
+		case trimmed == end && inBlock:
+			inBlock = false
+		case inBlock:
+			pending = append(pending, line)
+		default:
+			kept = append(kept, line)
+		}
+	}
+
+	
+	if inBlock {
+		kept = append(kept, pending...)
+	}
+
+	for len(kept) > 0 && kept[len(kept)-1] == "" {
+		kept = kept[:len(kept)-1]
+	}
+
+	if insertAt < 0 || insertAt > len(kept) {
+		if len(kept) > 0 && block != "" {
+			kept = append(kept, "")
+		}
+		insertAt = len(kept)
+	}
+
+	result := append([]string{}, kept[:insertAt]...)
+	if block != "" {
+		result = append(result, block)
+	}
+	result = append(result, kept[insertAt:]...)
+	if len(result) == 0 {
+		return ""
+	}
+	return strings.Join(result, "\n") + "\n"
+}
@@ -0,0 +1,74 @@
+package util
+
+import "testing"
+
+const (
+	testBegin = "# BEGIN liftoff"
+	testEnd   = "# END liftoff"
+)
+
+func TestReplaceManagedBlock(t *testing.T) {
+	block := testBegin + "\nmanaged\n" + testEnd
+
+	tests := []struct {
+		name    string
+		content string
+		block   string
+		want    string
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			block:   block,
+			want:    block + "\n",
+		},
+		{
+			name:    "no existing block",
+			content: "first\n  indented\n",
+			block:   block,
+			want:    "first\n  indented\n\n" + block + "\n",
+		},
+		{
+			name:    "existing block replaced in place",
+			content: "first\n" + testBegin + "\nold\n" + testEnd + "\nlast\n",
+			block:   block,
+			want:    "first\n" + block + "\nlast\n",
+		},
+		{
+			name:    "CRLF input",
+			content: "first\r\n" + testBegin + "\r\nold\r\n" + testEnd + "\r\n",
+			block:   block,
+			want:    "first\n" + block + "\n",
+		},
+		{
+			name:    "empty block removes existing block",
+			content: "first\n\n" + testBegin + "\nold\n" + testEnd + "\n",
+			block:   "",
+			want:    "first\n",
+		},
+		{
+			name:    "missing END marker keeps following lines",
+			content: "first\n" + testBegin + "\nold\nuser line\n",
+			block:   block,
+			want:    "first\n" + block + "\nold\nuser line\n",
+		},
+		{
+			name:    "duplicate blocks collapse into the first",
+			content: testBegin + "\na\n" + testEnd + "\nmiddle\n" + testBegin + "\nb\n" + testEnd + "\n",
+			block:   block,
+			want:    block + "\nmiddle\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReplaceManagedBlock(tt.content, tt.block, testBegin, testEnd)
+			if got != tt.want {
+				t.Errorf("ReplaceManagedBlock() = %q, want %q", got, tt.want)
+			}
+			if again := ReplaceManagedBlock(got, tt.block, testBegin, testEnd); again != got {
+				t.Errorf("second run changed the content: %q", again)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"cat2/liftoff/types"
@@ -65,16 +66,44 @@ func validateConfig(config *types.Config, log *Logger) error {
 	}
 
 	
-	for key, value := range config.Environment.Variables {
-		config.Environment.Variables[key] = os.ExpandEnv(value)
+	for key, variable := range config.Environment.Variables {
+		variable.Value = os.ExpandEnv(variable.Value)
+		config.Environment.Variables[key] = variable
 	}
+	proxyEnvironment(config, log)
 	for i, path := range config.Environment.PathAppend {
 		config.Environment.PathAppend[i] = os.ExpandEnv(path)
 	}
+	for i, path := range config.Environment.PathPrepend {
+		config.Environment.PathPrepend[i] = os.ExpandEnv(path)
+	}
+
+	
+	for i, dist := range config.WSL.Distributions {
+		source := os.ExpandEnv(dist.Source)
+		if source == "" && len(dist.SourceURLs) > 0 {
+			source = dist.SourceURLs[0]
+		}
+		config.WSL.Distributions[i].Source = source
+		config.WSL.Distributions[i].Location = os.ExpandEnv(dist.Location)
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			config.WSL.Distributions[i].SourceURLs = ExpandMirrors(append([]string{source}, dist.SourceURLs...), config.Mirrors.Rewrites)
+		}
+	}
 
 	
 	for i, file := range config.Downloads.Files {
 		config.Downloads.Files[i].Dest = os.ExpandEnv(file.Dest)
+		config.Downloads.Files[i].URLs = ExpandMirrors(append([]string{file.URL}, file.URLs...), config.Mirrors.Rewrites)
+		if len(config.Downloads.Files[i].URLs) == 0 {
+			return fmt.Errorf("download to %s has no URL", file.Dest)
+		}
+		if file.URL == "" {
+			config.Downloads.Files[i].URL = file.URLs[0]
+		}
+		if file.Signature != (types.SignatureConfig{}) && file.Signature.URL == "" {
+			return fmt.Errorf("download to %s has a signature without a url", file.Dest)
+		}
 	}
 
 	
@@ -97,6 +126,13 @@ func validateConfig(config *types.Config, log *Logger) error {
 	
 	for i, repo := range config.Git.Repositories {
 		config.Git.Repositories[i].Path = os.ExpandEnv(repo.Path)
+		config.Git.Repositories[i].URLs = ExpandMirrors(append([]string{repo.URL}, repo.URLs...), config.Mirrors.Rewrites)
+		if len(config.Git.Repositories[i].URLs) == 0 {
+			return fmt.Errorf("repository at %s has no URL", repo.Path)
+		}
+		if repo.URL == "" {
+			config.Git.Repositories[i].URL = repo.URLs[0]
+		}
 	}
 
 	
@@ -110,6 +146,102 @@ func validateConfig(config *types.Config, log *Logger) error {
 }
 
 
+
+func proxyEnvironment(config *types.Config, log *Logger) {
+	proxy := config.Network.Proxy
+	if !proxy.Enable || proxy.Server == "" {
+		return
+	}
+
+	address := fmt.Sprintf("%s:%d", proxy.Server, proxy.Port)
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+
+	var noProxy []string
+	for _, entry := range proxy.Bypass {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || entry == "<local>" {
+			continue
+		}
+		translated, ok := noProxyEntry(entry)
+		if !ok {
+			log.Warn(fmt.Sprintf("Proxy bypass %s has no NO_PROXY equivalent, skipping it", entry))
+			continue
+		}
+		noProxy = append(noProxy, translated)
+	}
+
+	values := map[string]string{"HTTP_PROXY": address, "HTTPS_PROXY": address}
+	if len(noProxy) > 0 {
+		values["NO_PROXY"] = strings.Join(noProxy, ",")
+	}
+
+	if config.Environment.Variables == nil {
+		config.Environment.Variables = make(map[string]types.EnvVariable)
+	}
+	for name, value := range values {
+		if userProxyVariable(config, name) {
+			log.Debug(fmt.Sprintf("%s is already defined, leaving it unchanged", name))
+			continue
+		}
+		config.Environment.Variables[name] = types.EnvVariable{Value: value}
+	}
+}
+
+func userProxyVariable(config *types.Config, name string) bool {
+	for existing := range config.Environment.Variables {
+		if strings.EqualFold(existing, name) {
+			return true
+		}
+	}
+	for _, entry := range config.Environment.Unset {
+		if strings.EqualFold(entry.Name, name) {
+			return true
+		}
+	}
+	if _, ok := os.LookupEnv(name); ok {
+		return true
+	}
+	_, ok := os.LookupEnv(strings.ToLower(name))
+	return ok
+}
+
+
+func noProxyEntry(entry string) (string, bool) {
+	if entry == "*" || !strings.Contains(entry, "*") {
+		return entry, true
+	}
+
+	if rest := strings.TrimPrefix(entry, "*"); strings.HasPrefix(rest, ".") && !strings.Contains(rest, "*") {
+		return rest, true
+	}
+
+	parts := strings.Split(entry, ".")
+	if len(parts) > 4 {
+		return "", false
+	}
+	var octets []string
+	for i, part := range parts {
+		if part == "*" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 255 || len(octets) != i {
+			return "", false
+		}
+		octets = append(octets, part)
+	}
+	if len(octets) == 0 {
+		return "", false
+	}
+	bits := len(octets) * 8
+	for len(octets) < 4 {
+		octets = append(octets, "0")
+	}
+	return fmt.Sprintf("%s/%d", strings.Join(octets, "."), bits), true
+}
+
 func expandAndAbsPath(path string) (string, error) {
 	expanded := os.ExpandEnv(path)
 	if !filepath.IsAbs(expanded) {
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"os"
+	"testing"
+
+	"cat2/liftoff/types"
+)
+
+func TestValidateConfigSignatureWithoutURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature types.SignatureConfig
+		wantErr   bool
+	}{
+		{name: "no signature", signature: types.SignatureConfig{}},
+		{name: "complete signature", signature: types.SignatureConfig{URL: "https://example.com/tool.zip.asc", Key: "release"}},
+		{name: "key without url", signature: types.SignatureConfig{Key: "release"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.Config{}
+			config.Downloads.Files = []types.DownloadFile{{
+				URL:       "https://example.com/tool.zip",
+				Dest:      "tool.zip",
+				Signature: tt.signature,
+			}}
+
+			err := validateConfig(&config, NewLogger(false))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNoProxyEntry(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  string
+		ok    bool
+	}{
+		{entry: "proxy.corp.com", want: "proxy.corp.com", ok: true},
+		{entry: "*.corp.com", want: ".corp.com", ok: true},
+		{entry: "*", want: "*", ok: true},
+		{entry: "10.*", want: "10.0.0.0/8", ok: true},
+		{entry: "192.168.*", want: "192.168.0.0/16", ok: true},
+		{entry: "172.16.*.*", want: "172.16.0.0/16", ok: true},
+		{entry: "10.1.2.*", want: "10.1.2.0/24", ok: true},
+		{entry: "10.*.1.*"},
+		{entry: "10.1*"},
+		{entry: "*corp*"},
+		{entry: "300.*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			got, ok := noProxyEntry(tt.entry)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("noProxyEntry(%q) = %q, %v, want %q, %v", tt.entry, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestProxyEnvironmentKeepsUserValues(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	config := types.Config{}
+	config.Network.Proxy = types.ProxyConfig{Enable: true, Server: "proxy.corp.com", Port: 8080, Bypass: []string{"<local>", "10.*", "*.corp.com", "*corp*"}}
+	config.Environment.Variables = map[string]types.EnvVariable{"https_proxy": {Value: "http://user.proxy:3128"}}
+	config.Environment.Unset = []types.EnvUnset{{Name: "HTTP_PROXY"}}
+
+	proxyEnvironment(&config, NewLogger(false))
+
+	if _, ok := config.Environment.Variables["HTTP_PROXY"]; ok {
+		t.Error("HTTP_PROXY was set although the config unsets it")
+	}
+	if _, ok := config.Environment.Variables["HTTPS_PROXY"]; ok {
+		t.Error("HTTPS_PROXY was added next to the user's https_proxy")
+	}
+	if got := config.Environment.Variables["https_proxy"].Value; got != "http://user.proxy:3128" {
+		t.Errorf("https_proxy = %q, want the user value", got)
+	}
+	if got := config.Environment.Variables["NO_PROXY"].Value; got != "10.0.0.0/8,.corp.com" {
+		t.Errorf("NO_PROXY = %q, want %q", got, "10.0.0.0/8,.corp.com")
+	}
+}
@@ -0,0 +1,30 @@
+package util
+
+import "strings"
+
+// ParseGitConfigValues splits the output of `git config -z --get-all` into
+// its values. Empty values are kept so that entries such as a blank
+// credential.helper, which resets the inherited helper list, still compare.
+func ParseGitConfigValues(output []byte) []string {
+	if len(output) == 0 {
+		return nil
+	}
+
+	values := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i, value := range values {
+		values[i] = strings.TrimRight(value, "\r")
+	}
+	return values
+}
+
+func GitConfigEqual(existing, desired []string) bool {
+	if len(existing) != len(desired) {
+		return false
+	}
+	for i := range existing {
+		if existing[i] != desired[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitConfigValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		desired []string
+		want    []string
+		equal   bool
+	}{
+		{name: "unset", output: "", desired: nil, want: nil, equal: true},
+		{name: "single value", output: "manager\x00", desired: []string{"manager"}, want: []string{"manager"}, equal: true},
+		{name: "empty then helper", output: "\x00manager\x00", desired: []string{"", "manager"}, want: []string{"", "manager"}, equal: true},
+		{name: "only empty", output: "\x00", desired: []string{""}, want: []string{""}, equal: true},
+		{name: "empty entry missing", output: "manager\x00", desired: []string{"", "manager"}, want: []string{"manager"}},
+		{name: "multiline value", output: "a\nb\x00", desired: []string{"a\nb"}, want: []string{"a\nb"}, equal: true},
+		{name: "different order", output: "b\x00a\x00", desired: []string{"a", "b"}, want: []string{"b", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseGitConfigValues([]byte(tt.output))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGitConfigValues(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+			if equal := GitConfigEqual(got, tt.desired); equal != tt.equal {
+				t.Errorf("GitConfigEqual(%q, %q) = %v, want %v", got, tt.desired, equal, tt.equal)
+			}
+		})
+	}
+}
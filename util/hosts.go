@@ -0,0 +1,126 @@
+package util
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+const maxHostsPerLine = 9
+
+
+func ParseHostsFile(content, begin, end string) (managed map[string][]string, unmanaged map[string]bool) {
+	managed = make(map[string][]string)
+	unmanaged = make(map[string]bool)
+
+	inBlock := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch trimmed {
+		case begin:
+			inBlock = true
+			continue
+		case end:
+			inBlock = false
+			continue
+		}
+
+		if i := strings.Index(trimmed, "#"); i >= 0 {
+			trimmed = trimmed[:i]
+		}
+		fields := strings.Fields(trimmed)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, hostname := range fields[1:] {
+			hostname = strings.ToLower(hostname)
+			if inBlock {
+				managed[hostname] = append(managed[hostname], fields[0])
+			} else {
+				unmanaged[hostname] = true
+			}
+		}
+	}
+
+	return managed, unmanaged
+}
+
+
+func HostsBlock(entries map[string][]string, begin, end string) (string, error) {
+	if len(entries) == 0 {
+		return "", nil
+	}
+
+	byAddr := make(map[netip.Addr][]string)
+	for hostname, ips := range entries {
+		if hostname == "" || strings.ContainsAny(hostname, " \t#") {
+			return "", fmt.Errorf("invalid hostname %q", hostname)
+		}
+		if len(ips) == 0 {
+			return "", fmt.Errorf("no IP address for %s", hostname)
+		}
+		addrs, err := parseHostAddrs(ips)
+		if err != nil {
+			return "", fmt.Errorf("%w for %s", err, hostname)
+		}
+		for _, addr := range addrs {
+			byAddr[addr] = append(byAddr[addr], hostname)
+		}
+	}
+
+	addrs := make([]netip.Addr, 0, len(byAddr))
+	for addr := range byAddr {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+
+	lines := []string{begin}
+	for _, addr := range addrs {
+		hostnames := byAddr[addr]
+		sort.Strings(hostnames)
+
+		for i := 0; i < len(hostnames); i += maxHostsPerLine {
+			last := min(i+maxHostsPerLine, len(hostnames))
+			lines = append(lines, addr.String()+"\t"+strings.Join(hostnames[i:last], " "))
+		}
+	}
+	lines = append(lines, end)
+
+	return strings.Join(lines, "\n"), nil
+}
+
+
+func SameHostAddrs(a, b []string) bool {
+	addrsA, errA := parseHostAddrs(a)
+	addrsB, errB := parseHostAddrs(b)
+	if errA != nil || errB != nil {
+		return strings.Join(a, " ") == strings.Join(b, " ")
+	}
+	if len(addrsA) != len(addrsB) {
+		return false
+	}
+	for i := range addrsA {
+		if addrsA[i] != addrsB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func parseHostAddrs(ips []string) ([]netip.Addr, error) {
+	seen := make(map[netip.Addr]bool)
+	var addrs []netip.Addr
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q", ip)
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	return addrs, nil
+}
@@ -0,0 +1,160 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHostsBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries map[string][]string
+		want    []string
+	}{
+		{
+			name:    "no entries",
+			entries: nil,
+			want:    nil,
+		},
+		{
+			name: "hosts grouped by address",
+			entries: map[string][]string{
+				"test.local": {"127.0.0.1"},
+				"dev.local":  {"127.0.0.1"},
+				"ipv6.local": {"::1"},
+			},
+			want: []string{"127.0.0.1\tdev.local test.local", "::1\tipv6.local"},
+		},
+		{
+			name: "IPv4 and IPv6 for one host",
+			entries: map[string][]string{
+				"dual.local": {"::1", "127.0.0.1"},
+			},
+			want: []string{"127.0.0.1\tdual.local", "::1\tdual.local"},
+		},
+		{
+			name: "duplicate addresses collapse",
+			entries: map[string][]string{
+				"dup.local": {"10.0.0.1", " 10.0.0.1"},
+			},
+			want: []string{"10.0.0.1\tdup.local"},
+		},
+		{
+			name: "long lines are split",
+			entries: map[string][]string{
+				"a0": {"10.0.0.1"}, "a1": {"10.0.0.1"}, "a2": {"10.0.0.1"}, "a3": {"10.0.0.1"},
+				"a4": {"10.0.0.1"}, "a5": {"10.0.0.1"}, "a6": {"10.0.0.1"}, "a7": {"10.0.0.1"},
+				"a8": {"10.0.0.1"}, "a9": {"10.0.0.1"},
+			},
+			want: []string{"10.0.0.1\ta0 a1 a2 a3 a4 a5 a6 a7 a8", "10.0.0.1\ta9"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HostsBlock(tt.entries, testBegin, testEnd)
+			if err != nil {
+				t.Fatalf("HostsBlock() error = %v", err)
+			}
+			want := ""
+			if tt.want != nil {
+				want = strings.Join(append(append([]string{testBegin}, tt.want...), testEnd), "\n")
+			}
+			if got != want {
+				t.Errorf("HostsBlock() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHostsBlockErrors(t *testing.T) {
+	tests := map[string]map[string][]string{
+		"invalid address":      {"bad.local": {"300.0.0.1"}},
+		"hostname with spaces": {"bad host": {"127.0.0.1"}},
+		"hostname with #":      {"bad#host": {"127.0.0.1"}},
+		"no address":           {"empty.local": nil},
+	}
+
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := HostsBlock(entries, testBegin, testEnd); err == nil {
+				t.Errorf("HostsBlock() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestHostsBlockRewrite(t *testing.T) {
+	hosts := "# Copyright (c) Microsoft Corp.\r\n" +
+		"127.0.0.1\tlocalhost\r\n" +
+		testBegin + "\r\n" +
+		"127.0.0.1\told.local stale.local\r\n" +
+		"10.0.0.1\tdual.local\r\n" +
+		testEnd + "\r\n" +
+		"192.168.1.5\tnas # added by hand\r\n"
+
+	managed, unmanaged := ParseHostsFile(hosts, testBegin, testEnd)
+	wantManaged := map[string][]string{
+		"old.local":   {"127.0.0.1"},
+		"stale.local": {"127.0.0.1"},
+		"dual.local":  {"10.0.0.1"},
+	}
+	if !reflect.DeepEqual(managed, wantManaged) {
+		t.Errorf("ParseHostsFile() managed = %v, want %v", managed, wantManaged)
+	}
+	if !unmanaged["localhost"] || !unmanaged["nas"] || unmanaged["old.local"] {
+		t.Errorf("ParseHostsFile() unmanaged = %v", unmanaged)
+	}
+
+	entries := map[string][]string{
+		"old.local":  {"127.0.0.1"},
+		"dual.local": {"10.0.0.1", "fd00::1"},
+	}
+	block, err := HostsBlock(entries, testBegin, testEnd)
+	if err != nil {
+		t.Fatalf("HostsBlock() error = %v", err)
+	}
+
+	want := "# Copyright (c) Microsoft Corp.\n" +
+		"127.0.0.1\tlocalhost\n" +
+		testBegin + "\n" +
+		"10.0.0.1\tdual.local\n" +
+		"127.0.0.1\told.local\n" +
+		"fd00::1\tdual.local\n" +
+		testEnd + "\n" +
+		"192.168.1.5\tnas # added by hand\n"
+	got := ReplaceManagedBlock(hosts, block, testBegin, testEnd)
+	if got != want {
+		t.Errorf("rewrite = %q, want %q", got, want)
+	}
+
+	managed, _ = ParseHostsFile(got, testBegin, testEnd)
+	if !SameHostAddrs(managed["dual.local"], []string{"fd00::1", "10.0.0.1"}) {
+		t.Errorf("dual.local = %v after rewrite", managed["dual.local"])
+	}
+	if _, ok := managed["stale.local"]; ok {
+		t.Errorf("stale.local survived the rewrite")
+	}
+	if again := ReplaceManagedBlock(got, block, testBegin, testEnd); again != got {
+		t.Errorf("second rewrite changed the file: %q", again)
+	}
+}
+
+func TestSameHostAddrs(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want bool
+	}{
+		{[]string{"127.0.0.1"}, []string{"127.0.0.1"}, true},
+		{[]string{"::1", "127.0.0.1"}, []string{"127.0.0.1", "::0:1"}, true},
+		{[]string{"127.0.0.1"}, []string{"127.0.0.1", "::1"}, false},
+		{[]string{"127.0.0.1"}, []string{"127.0.0.2"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := SameHostAddrs(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameHostAddrs(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+
+func FormatINIValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+
+func FormatINI(sections map[string]map[string]interface{}) string {
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("[" + name + "]\n")
+
+		keys := make([]string, 0, len(sections[name]))
+		for key := range sections[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			b.WriteString(key + " = " + FormatINIValue(sections[name][key]) + "\n")
+		}
+	}
+	return b.String()
+}
+
+type INIChange struct {
+	Key string
+	Old string
+	New string
+}
+
+
+func MergeINI(content, section string, values map[string]string) (string, []INIChange) {
+	crlf := strings.Contains(content, "\r\n")
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	pending := make(map[string]string, len(values))
+	for key := range values {
+		pending[strings.ToLower(key)] = key
+	}
+
+	var changes []INIChange
+	var result []string
+	inSection, found := false, false
+	insertAt := -1
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			if inSection {
+				insertAt = lastContentLine(result) + 1
+			}
+			inSection = strings.EqualFold(strings.TrimSpace(trimmed[1:len(trimmed)-1]), section)
+			found = found || inSection
+			result = append(result, line)
+			continue
+		}
+
+		if inSection && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, ";") {
+			name, old, ok := strings.Cut(trimmed, "=")
+			name, old = strings.TrimSpace(name), strings.TrimSpace(old)
+			if key, managed := pending[strings.ToLower(name)]; ok && managed {
+				delete(pending, strings.ToLower(name))
+				old, comment := splitINIComment(old)
+				if old != values[key] {
+					changes = append(changes, INIChange{Key: key, Old: old, New: values[key]})
+					line = name + "=" + values[key] + comment
+				}
+			}
+		}
+		result = append(result, line)
+	}
+	if inSection {
+		insertAt = lastContentLine(result) + 1
+	}
+
+	var added []string
+	for _, key := range sortedINIKeys(pending) {
+		added = append(added, key+"="+values[key])
+		changes = append(changes, INIChange{Key: key, New: values[key]})
+	}
+
+	switch {
+	case len(added) == 0:
+	case found:
+		result = append(result[:insertAt], append(added, result[insertAt:]...)...)
+	default:
+		if len(result) > 0 && strings.TrimSpace(result[len(result)-1]) != "" {
+			result = append(result, "")
+		}
+		result = append(result, "["+section+"]")
+		result = append(result, added...)
+	}
+
+	merged := strings.Join(result, "\n") + "\n"
+	if crlf {
+		merged = strings.ReplaceAll(merged, "\n", "\r\n")
+	}
+	return merged, changes
+}
+
+func MergeINISections(content string, sections map[string]map[string]interface{}) (string, []INIChange) {
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []INIChange
+	for _, name := range names {
+		values := make(map[string]string, len(sections[name]))
+		for key, value := range sections[name] {
+			values[key] = FormatINIValue(value)
+		}
+
+		var sectionChanges []INIChange
+		content, sectionChanges = MergeINI(content, name, values)
+		for _, change := range sectionChanges {
+			change.Key = name + "." + change.Key
+			changes = append(changes, change)
+		}
+	}
+	return content, changes
+}
+
+func splitINIComment(value string) (string, string) {
+	for i := 1; i < len(value); i++ {
+		if (value[i] == '#' || value[i] == ';') && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimSpace(value[:i]), " " + value[i:]
+		}
+	}
+	return value, ""
+}
+
+func lastContentLine(lines []string) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortedINIKeys(pending map[string]string) []string {
+	keys := make([]string, 0, len(pending))
+	for _, key := range pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
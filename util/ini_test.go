@@ -0,0 +1,145 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		values  map[string]string
+		want    string
+		changes []INIChange
+	}{
+		{
+			name:    "missing key added to the section",
+			content: "[wsl2]\nmemory=4GB\n\n[other]\nkey=value\n",
+			values:  map[string]string{"processors": "4"},
+			want:    "[wsl2]\nmemory=4GB\nprocessors=4\n\n[other]\nkey=value\n",
+			changes: []INIChange{{Key: "processors", New: "4"}},
+		},
+		{
+			name:    "existing key updated in place",
+			content: "[wsl2]\nmemory = 4GB\nswap=0\n",
+			values:  map[string]string{"memory": "8GB"},
+			want:    "[wsl2]\nmemory=8GB\nswap=0\n",
+			changes: []INIChange{{Key: "memory", Old: "4GB", New: "8GB"}},
+		},
+		{
+			name:    "key names match case-insensitively",
+			content: "[WSL2]\nMemory=4GB\n",
+			values:  map[string]string{"memory": "8GB"},
+			want:    "[WSL2]\nMemory=8GB\n",
+			changes: []INIChange{{Key: "memory", Old: "4GB", New: "8GB"}},
+		},
+		{
+			name:    "new section appended",
+			content: "[experimental]\nsparseVhd=true\n",
+			values:  map[string]string{"swap": "0", "memory": "8GB"},
+			want:    "[experimental]\nsparseVhd=true\n\n[wsl2]\nmemory=8GB\nswap=0\n",
+			changes: []INIChange{{Key: "memory", New: "8GB"}, {Key: "swap", New: "0"}},
+		},
+		{
+			name:    "new section in an empty file",
+			content: "",
+			values:  map[string]string{"memory": "8GB"},
+			want:    "[wsl2]\nmemory=8GB\n",
+			changes: []INIChange{{Key: "memory", New: "8GB"}},
+		},
+		{
+			name:    "comments kept in place",
+			content: "# global comment\n[wsl2]\n; memory limit\nmemory=4GB\n# memory=2GB\nswap=0 \n\n[other]\n# trailing\n",
+			values:  map[string]string{"memory": "8GB", "processors": "2"},
+			want:    "# global comment\n[wsl2]\n; memory limit\nmemory=8GB\n# memory=2GB\nswap=0 \nprocessors=2\n\n[other]\n# trailing\n",
+			changes: []INIChange{{Key: "memory", Old: "4GB", New: "8GB"}, {Key: "processors", New: "2"}},
+		},
+		{
+			name:    "no change when values already match",
+			content: "[wsl2]\r\nmemory = 8GB\r\nswap=0\r\n",
+			values:  map[string]string{"memory": "8GB", "swap": "0"},
+			want:    "[wsl2]\r\nmemory = 8GB\r\nswap=0\r\n",
+			changes: nil,
+		},
+		{
+			name:    "inline comments ignored when comparing",
+			content: "[wsl2]\nmemory=8GB # half of the host\nswap=0\t; disabled\n",
+			values:  map[string]string{"memory": "8GB", "swap": "0"},
+			want:    "[wsl2]\nmemory=8GB # half of the host\nswap=0\t; disabled\n",
+			changes: nil,
+		},
+		{
+			name:    "inline comment kept when the value changes",
+			content: "[wsl2]\nmemory=4GB # half of the host\n",
+			values:  map[string]string{"memory": "8GB"},
+			want:    "[wsl2]\nmemory=8GB # half of the host\n",
+			changes: []INIChange{{Key: "memory", Old: "4GB", New: "8GB"}},
+		},
+		{
+			name:    "key case differences are not changes",
+			content: "[WSL2]\nMemory=8GB\nnestedVirtualization=true\n",
+			values:  map[string]string{"memory": "8GB", "nestedvirtualization": "true"},
+			want:    "[WSL2]\nMemory=8GB\nnestedVirtualization=true\n",
+			changes: nil,
+		},
+		{
+			name:    "CRLF line endings kept",
+			content: "[wsl2]\r\nmemory=4GB\r\n",
+			values:  map[string]string{"swap": "0"},
+			want:    "[wsl2]\r\nmemory=4GB\r\nswap=0\r\n",
+			changes: []INIChange{{Key: "swap", New: "0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes := MergeINI(tt.content, "wsl2", tt.values)
+			if got != tt.want {
+				t.Errorf("MergeINI() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("MergeINI() changes = %#v, want %#v", changes, tt.changes)
+			}
+
+			again, changes := MergeINI(got, "wsl2", tt.values)
+			if again != got || len(changes) > 0 {
+				t.Errorf("second MergeINI() = %q with changes %v, want no change", again, changes)
+			}
+		})
+	}
+}
+
+func TestFormatINI(t *testing.T) {
+	got := FormatINI(map[string]map[string]interface{}{
+		"user": {"default": "dev"},
+		"boot": {"systemd": true, "command": nil},
+	})
+	want := "[boot]\ncommand = \nsystemd = true\n\n[user]\ndefault = dev\n"
+	if got != want {
+		t.Errorf("FormatINI() = %q, want %q", got, want)
+	}
+}
+
+func TestMergeINISections(t *testing.T) {
+	content := "[boot]\nsystemd=true\n\n[interop]\nenabled=false\nappendWindowsPath=false\n\n[user]\ndefault=root\n"
+	sections := map[string]map[string]interface{}{
+		"user":      {"default": "dev"},
+		"automount": {"enabled": true},
+	}
+
+	got, changes := MergeINISections(content, sections)
+	want := "[boot]\nsystemd=true\n\n[interop]\nenabled=false\nappendWindowsPath=false\n\n[user]\ndefault=dev\n\n[automount]\nenabled=true\n"
+	if got != want {
+		t.Errorf("MergeINISections() = %q, want %q", got, want)
+	}
+	wantChanges := []INIChange{{Key: "automount.enabled", New: "true"}, {Key: "user.default", Old: "root", New: "dev"}}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("MergeINISections() changes = %#v, want %#v", changes, wantChanges)
+	}
+
+	again, changes := MergeINISections(got, sections)
+	if again != got || len(changes) > 0 {
+		t.Errorf("second MergeINISections() = %q with changes %v, want no change", again, changes)
+	}
+}
@@ -0,0 +1,412 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"cat2/liftoff/types"
+)
+
+const (
+	RegNone     uint32 = 0
+	RegSZ       uint32 = 1
+	RegExpandSZ uint32 = 2
+	RegBinary   uint32 = 3
+	RegDWord    uint32 = 4
+	RegMultiSZ  uint32 = 7
+	RegQWord    uint32 = 11
+
+	regHeaderV5 = "Windows Registry Editor Version 5.00"
+	regHeaderV4 = "REGEDIT4"
+)
+
+type RegValue struct {
+	Name   string
+	Type   uint32
+	Data   []byte
+	Delete bool
+}
+
+type RegKey struct {
+	Path   string
+	Delete bool
+	Values []RegValue
+}
+
+func ParseRegFile(data []byte) ([]RegKey, error) {
+	text, err := decodeRegText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	header := ""
+	start := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			header = strings.TrimSpace(line)
+			start = i + 1
+			break
+		}
+	}
+
+	unicode := true
+	switch header {
+	case regHeaderV5:
+	case regHeaderV4:
+		unicode = false
+	default:
+		return nil, fmt.Errorf("not a .reg file: unexpected header %q", header)
+	}
+
+	var keys []RegKey
+	var current *RegKey
+
+	for i := start; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) && !strings.HasPrefix(line, "[") {
+			i++
+			line = strings.TrimSuffix(line, "\\") + strings.TrimSpace(lines[i])
+		}
+
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.LastIndex(line, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated key name", lineNo)
+			}
+			path := line[1:end]
+			key := RegKey{Path: path}
+			if strings.HasPrefix(path, "-") {
+				key.Path = path[1:]
+				key.Delete = true
+			}
+			keys = append(keys, key)
+			current = &keys[len(keys)-1]
+			continue
+		}
+
+		if current == nil {
+			return nil, fmt.Errorf("line %d: value outside of a key", lineNo)
+		}
+		if current.Delete {
+			return nil, fmt.Errorf("line %d: value under a deleted key", lineNo)
+		}
+
+		value, err := parseRegValue(line, unicode)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		current.Values = append(current.Values, value)
+	}
+
+	return keys, nil
+}
+
+func decodeRegText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		body := data[2:]
+		if len(body)%2 != 0 {
+			return "", fmt.Errorf("invalid UTF-16 data")
+		}
+		units := make([]uint16, len(body)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(body[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	default:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	}
+}
+
+func parseRegValue(line string, unicode bool) (RegValue, error) {
+	var value RegValue
+	var rest string
+
+	switch {
+	case strings.HasPrefix(line, "@"):
+		rest = strings.TrimSpace(line[1:])
+	case strings.HasPrefix(line, "\""):
+		name, n, err := parseRegString(line)
+		if err != nil {
+			return value, err
+		}
+		value.Name = name
+		rest = strings.TrimSpace(line[n:])
+	default:
+		return value, fmt.Errorf("invalid value line %q", line)
+	}
+
+	if !strings.HasPrefix(rest, "=") {
+		return value, fmt.Errorf("missing '=' in %q", line)
+	}
+	rest = strings.TrimSpace(rest[1:])
+
+	switch {
+	case rest == "-":
+		value.Delete = true
+	case strings.HasPrefix(rest, "\""):
+		str, _, err := parseRegString(rest)
+		if err != nil {
+			return value, err
+		}
+		value.Type = RegSZ
+		value.Data = encodeRegString(str)
+	case strings.HasPrefix(strings.ToLower(rest), "dword:"):
+		n, err := strconv.ParseUint(strings.TrimSpace(rest[6:]), 16, 32)
+		if err != nil {
+			return value, fmt.Errorf("invalid dword %q", rest[6:])
+		}
+		value.Type = RegDWord
+		value.Data = binary.LittleEndian.AppendUint32(nil, uint32(n))
+	case strings.HasPrefix(strings.ToLower(rest), "hex"):
+		valueType, data, err := parseRegHex(rest)
+		if err != nil {
+			return value, err
+		}
+		if !unicode && (valueType == RegExpandSZ || valueType == RegMultiSZ || valueType == RegSZ) {
+			data = ansiToUTF16(data)
+		}
+		value.Type = valueType
+		value.Data = data
+	default:
+		return value, fmt.Errorf("unsupported value data %q", rest)
+	}
+
+	return value, nil
+}
+
+func parseRegString(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("unterminated escape in %q", s)
+			}
+			i++
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string %q", s)
+}
+
+func parseRegHex(s string) (uint32, []byte, error) {
+	colon := strings.Index(s, ":")
+	if colon < 0 {
+		return 0, nil, fmt.Errorf("invalid hex value %q", s)
+	}
+
+	prefix := strings.ToLower(s[:colon])
+	valueType := RegBinary
+	if prefix != "hex" {
+		if !strings.HasPrefix(prefix, "hex(") || !strings.HasSuffix(prefix, ")") {
+			return 0, nil, fmt.Errorf("invalid hex type %q", prefix)
+		}
+		n, err := strconv.ParseUint(prefix[4:len(prefix)-1], 16, 32)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid hex type %q", prefix)
+		}
+		valueType = uint32(n)
+	}
+
+	switch valueType {
+	case RegSZ, RegExpandSZ, RegBinary, RegDWord, RegMultiSZ, RegQWord:
+	default:
+		return 0, nil, fmt.Errorf("unsupported registry type hex(%x)", valueType)
+	}
+
+	cleaned := strings.NewReplacer(",", "", " ", "", "\t", "").Replace(s[colon+1:])
+	data, err := hex.DecodeString(cleaned)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid hex data: %w", err)
+	}
+	return valueType, data, nil
+}
+
+func encodeRegString(s string) []byte {
+	units := append(utf16.Encode([]rune(s)), 0)
+	data := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		data = binary.LittleEndian.AppendUint16(data, u)
+	}
+	return data
+}
+
+func decodeRegString(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(data[i:]))
+	}
+	for len(units) > 0 && units[len(units)-1] == 0 {
+		units = units[:len(units)-1]
+	}
+	return string(utf16.Decode(units))
+}
+
+func ansiToUTF16(data []byte) []byte {
+	runes := make([]rune, 0, len(data))
+	for _, b := range data {
+		runes = append(runes, rune(b))
+	}
+	return encodeRegString(strings.TrimRight(string(runes), "\x00"))
+}
+
+func RegKeysToConfig(keys []RegKey) ([]types.RegistryConfig, error) {
+	var configs []types.RegistryConfig
+
+	for _, key := range keys {
+		root, path, _ := strings.Cut(key.Path, `\`)
+
+		if key.Delete {
+			if strings.Trim(path, `\ `) == "" {
+				return nil, fmt.Errorf("%s: refusing to delete a registry hive", key.Path)
+			}
+			configs = append(configs, types.RegistryConfig{Root: root, Path: path, State: "absent"})
+			continue
+		}
+
+		for _, value := range key.Values {
+			name := value.Name
+			if name == "" {
+				name = "@"
+			}
+			config := types.RegistryConfig{Root: root, Path: path, Name: name}
+
+			if value.Delete {
+				config.State = "absent"
+				configs = append(configs, config)
+				continue
+			}
+
+			switch value.Type {
+			case RegSZ:
+				config.Type, config.Value = "sz", decodeRegString(value.Data)
+			case RegExpandSZ:
+				config.Type, config.Value = "expand_sz", decodeRegString(value.Data)
+			case RegMultiSZ:
+				items := strings.Split(decodeRegString(value.Data), "\x00")
+				for len(items) > 0 && items[len(items)-1] == "" {
+					items = items[:len(items)-1]
+				}
+				config.Type, config.Value = "multi_sz", items
+			case RegDWord:
+				if len(value.Data) != 4 {
+					return nil, fmt.Errorf("%s\\%s: dword must be 4 bytes", key.Path, name)
+				}
+				config.Type, config.Value = "dword", binary.LittleEndian.Uint32(value.Data)
+			case RegQWord:
+				if len(value.Data) != 8 {
+					return nil, fmt.Errorf("%s\\%s: qword must be 8 bytes", key.Path, name)
+				}
+				config.Type, config.Value = "qword", binary.LittleEndian.Uint64(value.Data)
+			case RegBinary:
+				config.Type, config.Value = "binary", value.Data
+			default:
+				return nil, fmt.Errorf("%s\\%s: unsupported registry type %d", key.Path, name, value.Type)
+			}
+			configs = append(configs, config)
+		}
+	}
+
+	return configs, nil
+}
+
+func FormatRegFile(keys []RegKey) []byte {
+	var b strings.Builder
+	b.WriteString(regHeaderV5 + "\r\n")
+
+	for _, key := range keys {
+		b.WriteString("\r\n")
+		if key.Delete {
+			b.WriteString("[-" + key.Path + "]\r\n")
+			continue
+		}
+		b.WriteString("[" + key.Path + "]\r\n")
+
+		for _, value := range key.Values {
+			name := "@"
+			if value.Name != "" {
+				name = quoteRegString(value.Name)
+			}
+
+			switch {
+			case value.Delete:
+				b.WriteString(name + "=-\r\n")
+			case value.Type == RegSZ && isPlainRegString(value.Data):
+				b.WriteString(name + "=" + quoteRegString(decodeRegString(value.Data)) + "\r\n")
+			case value.Type == RegDWord && len(value.Data) == 4:
+				b.WriteString(fmt.Sprintf("%s=dword:%08x\r\n", name, binary.LittleEndian.Uint32(value.Data)))
+			case value.Type == RegBinary:
+				b.WriteString(formatRegHex(name+"=hex:", value.Data))
+			default:
+				b.WriteString(formatRegHex(fmt.Sprintf("%s=hex(%x):", name, value.Type), value.Data))
+			}
+		}
+	}
+	b.WriteString("\r\n")
+
+	return encodeUTF16File(b.String())
+}
+
+func isPlainRegString(data []byte) bool {
+	return len(data) >= 2 && len(data)%2 == 0 &&
+		!strings.ContainsAny(decodeRegString(data), "\x00\r\n")
+}
+
+func quoteRegString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+func formatRegHex(prefix string, data []byte) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	width := len(prefix)
+
+	for i, v := range data {
+		item := fmt.Sprintf("%02x", v)
+		if i < len(data)-1 {
+			item += ","
+		}
+		if width+len(item) > 77 && i < len(data)-1 {
+			b.WriteString("\\\r\n  ")
+			width = 2
+		}
+		b.WriteString(item)
+		width += len(item)
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func encodeUTF16File(s string) []byte {
+	data := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		data = binary.LittleEndian.AppendUint16(data, u)
+	}
+	return data
+}
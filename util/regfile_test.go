@@ -0,0 +1,207 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cat2/liftoff/types"
+)
+
+const testRegKey = `HKEY_CURRENT_USER\Software\Liftoff`
+
+func regText(lines ...string) []byte {
+	return []byte(strings.Join(append([]string{regHeaderV5, ""}, lines...), "\r\n") + "\r\n")
+}
+
+func TestParseRegFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []RegKey
+	}{
+		{
+			name: "UTF-16LE with BOM",
+			data: encodeUTF16File(string(regText("["+testRegKey+"]", `"Name"="välue"`))),
+			want: []RegKey{{Path: testRegKey, Values: []RegValue{
+				{Name: "Name", Type: RegSZ, Data: encodeRegString("välue")},
+			}}},
+		},
+		{
+			name: "UTF-8 with BOM",
+			data: append([]byte{0xEF, 0xBB, 0xBF}, regText("["+testRegKey+"]", `"Name"="value"`)...),
+			want: []RegKey{{Path: testRegKey, Values: []RegValue{
+				{Name: "Name", Type: RegSZ, Data: encodeRegString("value")},
+			}}},
+		},
+		{
+			name: "line continuations",
+			data: regText("["+testRegKey+"]", `"Binary"=hex:01,02,\`, `  03,04,\`, `  05`),
+			want: []RegKey{{Path: testRegKey, Values: []RegValue{
+				{Name: "Binary", Type: RegBinary, Data: []byte{1, 2, 3, 4, 5}},
+			}}},
+		},
+		{
+			name: "typed values",
+			data: regText("["+testRegKey+"]",
+				`"Expand"=hex(2):25,00,41,00,25,00,00,00`,
+				`"Multi"=hex(7):61,00,00,00,62,00,00,00,00,00`,
+				`"Count"=dword:0000002a`,
+				`"Big"=hex(b):01,00,00,00,00,00,00,00`,
+			),
+			want: []RegKey{{Path: testRegKey, Values: []RegValue{
+				{Name: "Expand", Type: RegExpandSZ, Data: encodeRegString("%A%")},
+				{Name: "Multi", Type: RegMultiSZ, Data: []byte{0x61, 0, 0, 0, 0x62, 0, 0, 0, 0, 0}},
+				{Name: "Count", Type: RegDWord, Data: []byte{0x2a, 0, 0, 0}},
+				{Name: "Big", Type: RegQWord, Data: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+			}}},
+		},
+		{
+			name: "default value",
+			data: regText("["+testRegKey+"]", `@="default"`),
+			want: []RegKey{{Path: testRegKey, Values: []RegValue{
+				{Name: "", Type: RegSZ, Data: encodeRegString("default")},
+			}}},
+		},
+		{
+			name: "deletions",
+			data: regText("[-"+testRegKey+`\Old]`, "", "["+testRegKey+"]", `"Gone"=-`, `@=-`),
+			want: []RegKey{
+				{Path: testRegKey + `\Old`, Delete: true},
+				{Path: testRegKey, Values: []RegValue{
+					{Name: "Gone", Delete: true},
+					{Name: "", Delete: true},
+				}},
+			},
+		},
+		{
+			name: "escaped quotes and backslashes",
+			data: regText("["+testRegKey+"]", `"Say \"hi\""="C:\\Tools\\\"quoted\""`),
+			want: []RegKey{{Path: testRegKey, Values: []RegValue{
+				{Name: `Say "hi"`, Type: RegSZ, Data: encodeRegString(`C:\Tools\"quoted"`)},
+			}}},
+		},
+		{
+			name: "comments and REGEDIT4 header",
+			data: []byte("REGEDIT4\r\n\r\n; comment\r\n[" + testRegKey + "]\r\n\"Expand\"=hex(2):25,41,25,00\r\n"),
+			want: []RegKey{{Path: testRegKey, Values: []RegValue{
+				{Name: "Expand", Type: RegExpandSZ, Data: encodeRegString("%A%")},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRegFile(tt.data)
+			if err != nil {
+				t.Fatalf("ParseRegFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRegFile() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRegFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"missing header", []byte("[" + testRegKey + "]\r\n")},
+		{"unterminated key", regText("[" + testRegKey)},
+		{"value outside of a key", regText(`"Name"="value"`)},
+		{"value under a deleted key", regText("[-"+testRegKey+"]", `"Name"="value"`)},
+		{"unterminated string", regText("["+testRegKey+"]", `"Name"="value`)},
+		{"invalid dword", regText("["+testRegKey+"]", `"Count"=dword:xyz`)},
+		{"unsupported hex type", regText("["+testRegKey+"]", `"Link"=hex(6):00`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRegFile(tt.data); err == nil {
+				t.Errorf("ParseRegFile() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestRegKeysToConfig(t *testing.T) {
+	keys, err := ParseRegFile(regText(
+		"[-"+testRegKey+`\Old]`,
+		"",
+		"["+testRegKey+"]",
+		`@="default"`,
+		`"Expand"=hex(2):25,00,41,00,25,00,00,00`,
+		`"Multi"=hex(7):61,00,00,00,62,00,00,00,00,00`,
+		`"Count"=dword:0000002a`,
+		`"Gone"=-`,
+	))
+	if err != nil {
+		t.Fatalf("ParseRegFile() error = %v", err)
+	}
+
+	got, err := RegKeysToConfig(keys)
+	if err != nil {
+		t.Fatalf("RegKeysToConfig() error = %v", err)
+	}
+
+	path := `Software\Liftoff`
+	want := []types.RegistryConfig{
+		{Root: "HKEY_CURRENT_USER", Path: path + `\Old`, State: "absent"},
+		{Root: "HKEY_CURRENT_USER", Path: path, Name: "@", Type: "sz", Value: "default"},
+		{Root: "HKEY_CURRENT_USER", Path: path, Name: "Expand", Type: "expand_sz", Value: "%A%"},
+		{Root: "HKEY_CURRENT_USER", Path: path, Name: "Multi", Type: "multi_sz", Value: []string{"a", "b"}},
+		{Root: "HKEY_CURRENT_USER", Path: path, Name: "Count", Type: "dword", Value: uint32(42)},
+		{Root: "HKEY_CURRENT_USER", Path: path, Name: "Gone", State: "absent"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RegKeysToConfig() = %#v, want %#v", got, want)
+	}
+}
+
+func TestRegKeysToConfigRefusesHiveDeletion(t *testing.T) {
+	for _, key := range []string{"[-HKEY_CURRENT_USER]", `[-HKEY_LOCAL_MACHINE\]`} {
+		keys, err := ParseRegFile(regText(key))
+		if err != nil {
+			t.Fatalf("ParseRegFile(%s) error = %v", key, err)
+		}
+		if _, err := RegKeysToConfig(keys); err == nil {
+			t.Errorf("RegKeysToConfig(%s) succeeded, want error", key)
+		}
+	}
+}
+
+func TestFormatRegFileRoundTrip(t *testing.T) {
+	long := make([]byte, 64)
+	for i := range long {
+		long[i] = byte(i)
+	}
+
+	keys := []RegKey{
+		{Path: testRegKey + `\Old`, Delete: true},
+		{Path: testRegKey, Values: []RegValue{
+			{Name: "", Type: RegSZ, Data: encodeRegString("default")},
+			{Name: `Say "hi"`, Type: RegSZ, Data: encodeRegString(`C:\Tools\"quoted"`)},
+			{Name: "Expand", Type: RegExpandSZ, Data: encodeRegString(`%USERPROFILE%\bin`)},
+			{Name: "Multi", Type: RegMultiSZ, Data: []byte{0x61, 0, 0, 0, 0x62, 0, 0, 0, 0, 0}},
+			{Name: "Count", Type: RegDWord, Data: []byte{0x2a, 0, 0, 0}},
+			{Name: "Big", Type: RegQWord, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+			{Name: "Long", Type: RegBinary, Data: long},
+			{Name: "Gone", Delete: true},
+		}},
+	}
+
+	data := FormatRegFile(keys)
+	if !strings.Contains(decodeRegString(data[2:]), "\\\r\n  ") {
+		t.Errorf("FormatRegFile() did not wrap the long binary value")
+	}
+
+	got, err := ParseRegFile(data)
+	if err != nil {
+		t.Fatalf("ParseRegFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, keys) {
+		t.Errorf("round trip = %#v, want %#v", got, keys)
+	}
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+
+func ResolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		path := os.ExpandEnv(strings.TrimPrefix(ref, "file:"))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %w", path, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return ref, nil
+	}
+}
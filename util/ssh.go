@@ -0,0 +1,84 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cat2/liftoff/types"
+)
+
+
+func PinnedHostKeys(hosts []types.GitHost) []string {
+	var pinned []string
+	for _, host := range hosts {
+		pinned = append(pinned, host.KnownHosts...)
+	}
+	return pinned
+}
+
+func WriteKnownHosts(path string, keys []string) error {
+	content := strings.Join(keys, "\n") + "\n"
+	return os.WriteFile(path, []byte(content), 0600)
+}
+
+
+func SSHConfigBlock(hosts []types.SSHHostBlock, knownHosts, begin, end string) string {
+	block := []string{begin}
+	for _, host := range hosts {
+		block = append(block, "Host "+host.Host)
+		if host.HostName != "" {
+			block = append(block, "    HostName "+host.HostName)
+		}
+		if host.User != "" {
+			block = append(block, "    User "+host.User)
+		}
+		if host.Port > 0 {
+			block = append(block, fmt.Sprintf("    Port %d", host.Port))
+		}
+		if host.IdentityFile != "" {
+			block = append(block, "    IdentityFile "+filepath.ToSlash(os.ExpandEnv(host.IdentityFile)))
+			block = append(block, "    IdentitiesOnly yes")
+		}
+		if knownHosts != "" {
+			block = append(block, "    UserKnownHostsFile "+QuoteSSHPath(knownHosts))
+			block = append(block, "    StrictHostKeyChecking yes")
+		}
+	}
+	block = append(block, end)
+	return strings.Join(block, "\n")
+}
+
+func UpdateSSHConfig(path, block, begin, end string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	content := ReplaceManagedBlock(string(existing), block, begin, end)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func QuoteSSHPath(path string) string {
+	return "\"" + filepath.ToSlash(path) + "\""
+}
+
+
+func GitEnv(sshCommand, knownHosts string) []string {
+	env := append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_SSL_NO_VERIFY=false",
+	)
+
+	if knownHosts != "" {
+		command := fmt.Sprintf("%s -o BatchMode=yes -o StrictHostKeyChecking=yes -o UserKnownHostsFile=%s",
+			sshCommand, QuoteSSHPath(knownHosts))
+		env = append(env, "GIT_SSH_COMMAND="+command)
+	}
+
+	return env
+}
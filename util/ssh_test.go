@@ -0,0 +1,188 @@
+package util
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"cat2/liftoff/types"
+)
+
+func TestWriteKnownHosts(t *testing.T) {
+	hosts := []types.GitHost{
+		{Host: "github.com"},
+		{Host: "git.example.com", AllowSSH: true, KnownHosts: []string{
+			"git.example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFirst",
+			"git.example.com ecdsa-sha2-nistp256 AAAAE2VjZHNhSecond",
+		}},
+		{Host: "*.corp.example.com", AllowSSH: true, KnownHosts: []string{
+			"@cert-authority *.corp.example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAICA",
+		}},
+	}
+
+	pinned := PinnedHostKeys(hosts)
+	if len(pinned) != 3 {
+		t.Fatalf("PinnedHostKeys() returned %d keys, want 3", len(pinned))
+	}
+
+	path := filepath.Join(t.TempDir(), "liftoff_known_hosts")
+	if err := os.WriteFile(path, []byte("stale entry\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteKnownHosts(path, pinned); err != nil {
+		t.Fatalf("WriteKnownHosts() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(pinned, "\n") + "\n"
+	if string(data) != want {
+		t.Errorf("known hosts = %q, want %q", data, want)
+	}
+}
+
+func TestUpdateSSHConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	knownHosts := "/home/dev/.ssh/liftoff_known_hosts"
+
+	hosts := []types.SSHHostBlock{
+		{Host: "gitlab", HostName: "gitlab.git.example.com", User: "git", Port: 2222, IdentityFile: "/keys/id_ed25519"},
+		{Host: "gitea.internal"},
+	}
+	block := SSHConfigBlock(hosts, knownHosts, testBegin, testEnd)
+
+	wantBlock := strings.Join([]string{
+		testBegin,
+		"Host gitlab",
+		"    HostName gitlab.git.example.com",
+		"    User git",
+		"    Port 2222",
+		"    IdentityFile /keys/id_ed25519",
+		"    IdentitiesOnly yes",
+		`    UserKnownHostsFile "/home/dev/.ssh/liftoff_known_hosts"`,
+		"    StrictHostKeyChecking yes",
+		"Host gitea.internal",
+		`    UserKnownHostsFile "/home/dev/.ssh/liftoff_known_hosts"`,
+		"    StrictHostKeyChecking yes",
+		testEnd,
+	}, "\n")
+	if block != wantBlock {
+		t.Fatalf("SSHConfigBlock() = %q, want %q", block, wantBlock)
+	}
+
+	if err := UpdateSSHConfig(path, block, testBegin, testEnd); err != nil {
+		t.Fatalf("UpdateSSHConfig() on a missing file error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != wantBlock+"\n" {
+		t.Errorf("new config = %q, want %q", data, wantBlock+"\n")
+	}
+
+	existing := "Host personal\n    User me\n\n" + testBegin + "\nHost old\n" + testEnd + "\n\nHost *\n    ServerAliveInterval 60\n"
+	if err := os.WriteFile(path, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateSSHConfig(path, block, testBegin, testEnd); err != nil {
+		t.Fatalf("UpdateSSHConfig() error = %v", err)
+	}
+
+	data, _ = os.ReadFile(path)
+	want := "Host personal\n    User me\n\n" + wantBlock + "\n\nHost *\n    ServerAliveInterval 60\n"
+	if string(data) != want {
+		t.Errorf("updated config = %q, want %q", data, want)
+	}
+
+	if err := UpdateSSHConfig(path, block, testBegin, testEnd); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != want {
+		t.Errorf("second update changed the config: %q", again)
+	}
+}
+
+func TestGitEnvWithoutPinnedKeys(t *testing.T) {
+	for _, entry := range GitEnv("ssh", "") {
+		if strings.HasPrefix(entry, "GIT_SSH_COMMAND=") && os.Getenv("GIT_SSH_COMMAND") == "" {
+			t.Errorf("GitEnv() set %s without pinned host keys", entry)
+		}
+	}
+}
+
+
+func TestGitEnvCloneOverSSH(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ssh command is a shell script")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	bare := filepath.Join(dir, "remote.git")
+	runTestGit(t, "", "init", "-q", work)
+	runTestGit(t, work, "-c", "user.name=Liftoff", "-c", "user.email=liftoff@example.com",
+		"commit", "-q", "--allow-empty", "-m", "initial")
+	runTestGit(t, "", "clone", "-q", "--bare", work, bare)
+
+	logPath := filepath.Join(dir, "ssh.log")
+	fakeSSH := filepath.Join(dir, "fake-ssh")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" >> '" + logPath + "'\nfor arg; do last=$arg; done\nexec sh -c \"$last\"\n"
+	if err := os.WriteFile(fakeSSH, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	knownHosts := filepath.Join(dir, "liftoff_known_hosts")
+	if err := WriteKnownHosts(knownHosts, []string{"git.example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAITest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "clone")
+	cmd := exec.Command("git", "clone", "-q", "git@git.example.com:"+bare, dest)
+	cmd.Env = append(GitEnv(fakeSSH, knownHosts), "GIT_SSH_VARIANT=ssh")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git clone failed: %v\n%s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err != nil {
+		t.Fatalf("clone has no .git directory: %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("fake ssh was not invoked: %v", err)
+	}
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, want := range []string{
+		"BatchMode=yes",
+		"StrictHostKeyChecking=yes",
+		"UserKnownHostsFile=" + knownHosts,
+		"git@git.example.com",
+	} {
+		if !containsString(args, want) {
+			t.Errorf("ssh arguments %q do not contain %q", args, want)
+		}
+	}
+}
+
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
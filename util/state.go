@@ -0,0 +1,55 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+
+func StateDir() string {
+	return filepath.Join(os.ExpandEnv("${USERPROFILE}"), "Liftoff", "State")
+}
+
+
+func LoadState(name string, v interface{}) error {
+	path := filepath.Join(StateDir(), name+".json")
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	return nil
+}
+
+
+func SaveState(name string, v interface{}) error {
+	dir := StateDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	path := filepath.Join(dir, name+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write state %s: %w", path, err)
+	}
+	return nil
+}
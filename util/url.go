@@ -11,6 +11,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"cat2/liftoff/types"
 )
 
 const (
@@ -147,27 +149,89 @@ func validateURL(urlStr string) error {
 	return nil
 }
 
-func validateGitURL(urlStr string) error {
-	if err := validateURL(urlStr); err != nil {
-		return err
-	}
+var DefaultGitHosts = []types.GitHost{
+	{Host: "github.com"},
+	{Host: "gitlab.com"},
+	{Host: "bitbucket.org"},
+	{Host: "dev.azure.com"},
+}
+
 
-	parsedURL, _ := url.Parse(urlStr)
-	host := strings.ToLower(parsedURL.Host)
+func ParseGitURL(urlStr string) (scheme, host string, err error) {
+	if strings.Contains(urlStr, "://") {
+		parsedURL, err := url.Parse(urlStr)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse URL: %w", err)
+		}
+		if strings.TrimSpace(parsedURL.Hostname()) == "" {
+			return "", "", fmt.Errorf("URL host is required")
+		}
+		return strings.ToLower(parsedURL.Scheme), strings.ToLower(parsedURL.Hostname()), nil
+	}
 
 	
-	trustedHosts := map[string]bool{
-		"github.com":    true,
-		"gitlab.com":    true,
-		"bitbucket.org": true,
-		"dev.azure.com": true,
+	colon := strings.Index(urlStr, ":")
+	if colon <= 0 || strings.ContainsAny(urlStr[:colon], "/\\") {
+		return "", "", fmt.Errorf("unrecognized git URL: %s", urlStr)
+	}
+	if colon == 1 {
+		
+		return "", "", fmt.Errorf("local paths are not git URLs: %s", urlStr)
+	}
+	host = urlStr[:colon]
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("URL host is required")
+	}
+	return "ssh", strings.ToLower(host), nil
+}
+
+func matchGitHost(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+
+func ValidateGitURL(urlStr string, trusted []types.GitHost) error {
+	scheme, host, err := ParseGitURL(urlStr)
+	if err != nil {
+		return err
+	}
+
+	if len(trusted) == 0 {
+		trusted = DefaultGitHosts
 	}
 
-	if !trustedHosts[host] {
+	var policy *types.GitHost
+	for i := range trusted {
+		if matchGitHost(trusted[i].Host, host) {
+			policy = &trusted[i]
+			break
+		}
+	}
+	if policy == nil {
 		return fmt.Errorf("untrusted Git host: %s", host)
 	}
 
-	return nil
+	switch scheme {
+	case "https":
+		return nil
+	case "ssh":
+		if !policy.AllowSSH {
+			return fmt.Errorf("SSH is not allowed for %s", host)
+		}
+		if len(policy.KnownHosts) == 0 {
+			return fmt.Errorf("no pinned SSH host key for %s", host)
+		}
+		return nil
+	default:
+		return fmt.Errorf("only HTTPS URLs are allowed")
+	}
 }
 
 func verifyChecksum(data []byte, expectedSHA256 string) error {
@@ -192,3 +256,41 @@ type DownloadConfig struct {
 	MaxSize        int64
 	Timeout        time.Duration
 }
+
+
+func RewriteURL(urlStr string, rules []types.URLRewrite) string {
+	best := -1
+	for i, rule := range rules {
+		if rule.Prefix == "" || !strings.HasPrefix(urlStr, rule.Prefix) {
+			continue
+		}
+		
+		if best < 0 || len(rule.Prefix) > len(rules[best].Prefix) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return urlStr
+	}
+	return rules[best].Mirror + strings.TrimPrefix(urlStr, rules[best].Prefix)
+}
+
+
+func ExpandMirrors(urls []string, rules []types.URLRewrite) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(u string) {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			result = append(result, u)
+		}
+	}
+
+	for _, u := range urls {
+		add(RewriteURL(u, rules))
+	}
+	for _, u := range urls {
+		add(u)
+	}
+	return result
+}
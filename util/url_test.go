@@ -0,0 +1,75 @@
+package util
+
+import (
+	"testing"
+
+	"cat2/liftoff/types"
+)
+
+func TestParseGitURL(t *testing.T) {
+	tests := []struct {
+		url        string
+		wantScheme string
+		wantHost   string
+		wantErr    bool
+	}{
+		{url: "https://github.com/org/repo.git", wantScheme: "https", wantHost: "github.com"},
+		{url: "https://GitLab.Example.com:8443/org/repo", wantScheme: "https", wantHost: "gitlab.example.com"},
+		{url: "ssh://git@git.example.com:2222/org/repo.git", wantScheme: "ssh", wantHost: "git.example.com"},
+		{url: "ssh://git.example.com/org/repo.git", wantScheme: "ssh", wantHost: "git.example.com"},
+		{url: "git@github.com:org/repo.git", wantScheme: "ssh", wantHost: "github.com"},
+		{url: "deploy@Gitea.internal:team/repo.git", wantScheme: "ssh", wantHost: "gitea.internal"},
+		{url: "gitea.internal:team/repo.git", wantScheme: "ssh", wantHost: "gitea.internal"},
+		{url: "./relative/path", wantErr: true},
+		{url: `C:\repos\local`, wantErr: true},
+		{url: "https:///missing-host", wantErr: true},
+		{url: "@:repo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			scheme, host, err := ParseGitURL(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseGitURL(%q) = %q, %q, want error", tt.url, scheme, host)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGitURL(%q) error: %v", tt.url, err)
+			}
+			if scheme != tt.wantScheme || host != tt.wantHost {
+				t.Errorf("ParseGitURL(%q) = %q, %q, want %q, %q", tt.url, scheme, host, tt.wantScheme, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestValidateGitURLSSH(t *testing.T) {
+	trusted := []types.GitHost{
+		{Host: "github.com", AllowSSH: true, KnownHosts: []string{"github.com ssh-ed25519 AAAA"}},
+		{Host: "*.corp.example", AllowSSH: true},
+		{Host: "gitlab.com"},
+	}
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "git@github.com:org/repo.git"},
+		{url: "ssh://git@github.com/org/repo.git"},
+		{url: "git@git.corp.example:org/repo.git", wantErr: true},
+		{url: "git@gitlab.com:org/repo.git", wantErr: true},
+		{url: "https://gitlab.com/org/repo.git"},
+		{url: "git@unknown.example:org/repo.git", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := ValidateGitURL(tt.url, trusted)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGitURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}